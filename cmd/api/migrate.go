@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"devices_api/internal/devices/postgres"
+)
+
+const migrateUsage = "usage: api migrate up|down|status|to N"
+
+// migrate runs the migrate subcommand against the postgres database
+// configured by the DB_* variables.
+func migrate(args []string) error {
+	var run func(m *postgres.Migrator, ctx context.Context) error
+	switch {
+	case len(args) == 1 && args[0] == "up":
+		run = (*postgres.Migrator).Up
+	case len(args) == 1 && args[0] == "down":
+		run = (*postgres.Migrator).Down
+	case len(args) == 2 && args[0] == "to":
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid migration %q: %w", args[1], err)
+		}
+		run = func(m *postgres.Migrator, ctx context.Context) error { return m.To(ctx, n) }
+	case len(args) == 1 && args[0] == "status":
+		run = func(*postgres.Migrator, context.Context) error { return nil }
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	cfg, err := postgres.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := postgres.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m := postgres.NewMigrator(db)
+	err = run(m, ctx)
+	if err != nil {
+		return err
+	}
+
+	return printStatus(ctx, m)
+}
+
+func printStatus(ctx context.Context, m *postgres.Migrator) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEQUENCE\tFILENAME\tAPPLIED")
+	for _, mg := range status {
+		applied := "pending"
+		if !mg.AppliedAt.IsZero() {
+			applied = mg.AppliedAt.Format(time.RFC3339)
+		}
+		if mg.Modified {
+			applied += " (modified since applied)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", mg.Sequence, mg.Filename, applied)
+	}
+	return w.Flush()
+}
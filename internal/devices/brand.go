@@ -0,0 +1,52 @@
+package devices
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrUnknownBrand = errors.New("unknown brand")
+	ErrInvalidBrand = errors.New("brand names and aliases need at least one letter or digit")
+	ErrBrandInUse   = errors.New("cannot delete brand while devices use it")
+)
+
+// Brand is the canonical entry for a device brand. Devices reference brands
+// by id, so renaming a brand renames it on every device.
+type Brand struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Aliases   []string  `json:"aliases"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateBrand represents the model to create a new brand.
+type CreateBrand struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// BrandSlug normalises a brand name or alias for lookups, so that "Apple",
+// "apple" and "APPLE " all resolve to "apple". Runs of characters other than
+// letters and digits become a single '-'.
+func BrandSlug(name string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCompositionCycle = errors.New("device cannot be placed inside itself or one of its parts")
+	ErrUnknownParent    = errors.New("unknown parent device")
+	ErrParentInUse      = errors.New("device is part of an in-use device")
+	ErrAssemblyInUse    = errors.New("cannot change the parts of an in-use device")
+	ErrDeviceHasParts   = errors.New("cannot delete device while it has parts attached")
+)
+
+// Attach represents the model to make a device part of another one, such
+// as a phone of a test rig. A zero ParentId detaches the device.
+type Attach struct {
+	ParentId int64 `json:"parent_id"`
+}
+
+// DeviceNode is a device together with its parts, as returned for the
+// hierarchy of an assembly.
+type DeviceNode struct {
+	Device
+	Children []DeviceNode `json:"children"`
+}
+
+// CheckCycle validates making the device deviceId a part of the device
+// whose ancestors, starting with the new parent itself, are given.
+func CheckCycle(deviceId int64, ancestors []int64) error {
+	for _, id := range ancestors {
+		if id == deviceId {
+			return ErrCompositionCycle
+		}
+	}
+	return nil
+}
+
+// CheckParentState refuses changes to a device while the assembly it is
+// part of is in use. parent is nil for devices without a parent.
+func (d *Device) CheckParentState(parent *Device) error {
+	if parent == nil || !parent.IsDeviceInUse() {
+		return nil
+	}
+	return fmt.Errorf("%w: device %d is part of device %d%s", ErrParentInUse, d.Id, parent.Id, parent.heldBy())
+}
+
+// BuildTree arranges the devices of an assembly below the device rootId.
+// Devices whose parent is not among dd are left out.
+func BuildTree(rootId int64, dd []Device) (*DeviceNode, error) {
+	children := map[int64][]Device{}
+	var root *Device
+	for i := range dd {
+		if dd[i].Id == rootId {
+			root = &dd[i]
+			continue
+		}
+		children[dd[i].ParentId] = append(children[dd[i].ParentId], dd[i])
+	}
+
+	if root == nil {
+		return nil, ErrNotExist
+	}
+
+	var build func(d Device) DeviceNode
+	build = func(d Device) DeviceNode {
+		n := DeviceNode{Device: d, Children: []DeviceNode{}}
+		for _, c := range children[d.Id] {
+			n.Children = append(n.Children, build(c))
+		}
+		return n
+	}
+
+	n := build(*root)
+	return &n, nil
+}
@@ -16,14 +16,65 @@ type Device struct {
 	Brand     string      `json:"brand"`
 	State     DeviceState `json:"state"`
 	CreatedAt time.Time   `json:"created_at"`
+
+	// BrandId references the canonical brand. Brand holds its name.
+	BrandId int64 `json:"brand_id,omitempty"`
+
+	// ModelId optionally references a model of the brand.
+	ModelId int64 `json:"model_id,omitempty"`
+
+	// SerialNumber and AssetTag identify the physical device. Both are
+	// optional, but no two devices share a value.
+	SerialNumber string `json:"serial_number,omitempty"`
+	AssetTag     string `json:"asset_tag,omitempty"`
+
+	// LocationId is where the device physically is. It changes through
+	// moves, which are recorded in the device history.
+	LocationId int64 `json:"location_id,omitempty"`
+
+	// OwnerId is the team that owns the device and AssigneeId the user
+	// currently working with it. Ownership changes through transfers the
+	// receiving team has to accept.
+	OwnerId    int64 `json:"owner_id,omitempty"`
+	AssigneeId int64 `json:"assignee_id,omitempty"`
+
+	// ParentId is the device this one is a part of, such as the test rig
+	// holding a phone. Parts follow the state of an in-use parent.
+	ParentId int64 `json:"parent_id,omitempty"`
+
+	Lifecycle
+
+	// Attributes holds free-form, team specific fields such as the OS
+	// version or the cost centre.
+	Attributes map[string]any `json:"attributes,omitempty"`
+
+	// Labels are identifying key/value pairs such as team=qa that can be
+	// queried with a label selector.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Version is incremented on every write and used for optimistic
+	// concurrency control.
+	Version int64 `json:"version"`
+
+	// DeletedAt is set while the device is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Lease is the active checkout of the device, if any.
+	Lease *Lease `json:"lease,omitempty"`
 }
 
+// DeviceState is the state of a device in its lifecycle. In JSON it is
+// written by name, such as "in_use"; the numbers, which follow the
+// declaration order, are still accepted on input.
+//
+// swagger:enum DeviceState
 type DeviceState int
 
 const (
 	Available DeviceState = iota
 	InUse
 	Inactive
+	Maintenance
 )
 
 const dateTimeApiLayout = time.RFC3339
@@ -1,6 +1,9 @@
 package devices
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"time"
@@ -15,11 +18,56 @@ func TestChangeDeviceState(t *testing.T) {
 
 	assert.Equal(t, d.State, Inactive)
 
-	d.ChangeDeviceState(InUse)
-	assert.Equal(t, d.State, InUse)
+	err := d.ChangeDeviceState(InUse)
+	assert.Equal(t, &ErrInvalidTransition{From: Inactive, To: InUse}, err)
+	assert.Equal(t, d.State, Inactive)
+
+	err = d.ChangeDeviceState(Available)
+	assert.NoError(t, err)
+	assert.Equal(t, d.State, Available)
 
-	d.ChangeDeviceState(Available)
+	err = d.ChangeDeviceState(InUse)
+	assert.NoError(t, err)
 	assert.Equal(t, d.State, InUse)
+
+	err = d.ChangeDeviceState(Available)
+	assert.NoError(t, err)
+	assert.Equal(t, d.State, Available)
+}
+
+func TestChangeDeviceState_Unknown(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+
+	err := d.ChangeDeviceState(DeviceState(42))
+	if assert.Error(t, err) {
+		assert.EqualError(t, err, "invalid device state transition from Inactive to DeviceState(42)")
+	}
+	assert.Equal(t, d.State, Inactive)
+}
+
+func TestChangeDeviceState_Same(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+
+	err := d.ChangeDeviceState(Inactive)
+	assert.NoError(t, err)
+	assert.Equal(t, d.State, Inactive)
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+	assert.Equal(t, []DeviceState{Available, Maintenance}, d.AllowedTransitions())
+
+	d.State = Available
+	assert.Equal(t, []DeviceState{InUse, Inactive, Maintenance}, d.AllowedTransitions())
+
+	d.State = InUse
+	assert.Equal(t, []DeviceState{Available}, d.AllowedTransitions())
+
+	d.State = Maintenance
+	assert.Equal(t, []DeviceState{Available, Inactive}, d.AllowedTransitions())
+
+	d.State = DeviceState(42)
+	assert.Empty(t, d.AllowedTransitions())
 }
 
 func TestChangeDeviceName_Success(t *testing.T) {
@@ -42,7 +90,8 @@ func TestChangeDeviceName_Fail(t *testing.T) {
 	err := d.ChangeDeviceName(newName)
 
 	if assert.Error(t, err) {
-		assert.Equal(t, "trying to change device %d name while in use", err)
+		assert.EqualError(t, err, "trying to change device 0 name while in use")
+		assert.ErrorIs(t, err, ErrDeviceInUseChange)
 	}
 }
 
@@ -65,7 +114,8 @@ func TestChangeDeviceBrand_Fail(t *testing.T) {
 	err := d.ChangeDeviceBrand(newBrand)
 
 	if assert.Error(t, err) {
-		assert.Equal(t, "trying to change device %d brand while in use", err)
+		assert.EqualError(t, err, "trying to change device 0 brand while in use")
+		assert.ErrorIs(t, err, ErrDeviceInUseChange)
 	}
 }
 
@@ -90,3 +140,431 @@ func TestCreationTimeFormatted(t *testing.T) {
 	expected := "2009-11-10T23:01:02Z"
 	assert.Equal(t, expected, formattedTime)
 }
+
+func TestCheckOut(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	until := now.Add(2 * time.Hour)
+
+	d := NewDevice("name01", "brand01")
+	_, err := d.CheckOut(Checkout{Holder: "alice", ExpectedReturnAt: until}, now)
+	assert.Equal(t, &ErrInvalidTransition{From: Inactive, To: InUse}, err)
+
+	d.State = Available
+	_, err = d.CheckOut(Checkout{ExpectedReturnAt: until}, now)
+	assert.ErrorIs(t, err, ErrInvalidCheckout)
+
+	_, err = d.CheckOut(Checkout{Holder: "alice", ExpectedReturnAt: now}, now)
+	assert.ErrorIs(t, err, ErrInvalidCheckout)
+
+	l, err := d.CheckOut(Checkout{Holder: "alice", ExpectedReturnAt: until}, now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, InUse, d.State)
+		assert.Equal(t, "alice", l.Holder)
+		assert.Equal(t, now, l.CheckedOutAt)
+		assert.Same(t, l, d.Lease)
+	}
+
+	_, err = d.CheckOut(Checkout{Holder: "bob", ExpectedReturnAt: until}, now)
+	assert.ErrorIs(t, err, ErrDeviceCheckedOut)
+
+	err = d.ChangeDeviceName("name02")
+	assert.EqualError(t, err, "trying to change device 0 name while in use by alice until 2024-03-01T11:00:00Z")
+}
+
+func TestCheckIn(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	d := NewDevice("name01", "brand01")
+	_, err := d.CheckIn(now)
+	assert.ErrorIs(t, err, ErrNotCheckedOut)
+
+	d.State = Available
+	_, err = d.CheckOut(Checkout{Holder: "alice", ExpectedReturnAt: now.Add(time.Hour)}, now)
+	assert.NoError(t, err)
+
+	l, err := d.CheckIn(now.Add(time.Minute))
+	if assert.NoError(t, err) {
+		assert.Equal(t, Available, d.State)
+		assert.Nil(t, d.Lease)
+		assert.Equal(t, now.Add(time.Minute), *l.ReturnedAt)
+	}
+}
+
+func TestLeaseExpired(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	l := &Lease{ExpectedReturnAt: now}
+
+	assert.False(t, l.Expired(now))
+	assert.True(t, l.Expired(now.Add(time.Second)))
+
+	l.ReturnedAt = &now
+	assert.False(t, l.Expired(now.Add(time.Second)))
+}
+
+func TestCreateReservationValidate(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	valid := CreateReservation{Holder: "qa", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)}
+	assert.NoError(t, valid.Validate(now))
+
+	noHolder := valid
+	noHolder.Holder = ""
+	assert.ErrorIs(t, noHolder.Validate(now), ErrInvalidReservation)
+
+	reversed := valid
+	reversed.StartsAt, reversed.EndsAt = valid.EndsAt, valid.StartsAt
+	assert.ErrorIs(t, reversed.Validate(now), ErrInvalidReservation)
+
+	past := CreateReservation{Holder: "qa", StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}
+	assert.ErrorIs(t, past.Validate(now), ErrInvalidReservation)
+}
+
+func TestCheckReserved(t *testing.T) {
+	start := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+	rr := []Reservation{{Holder: "qa", StartsAt: start, EndsAt: start.Add(time.Hour)}}
+
+	// Windows touching the reservation at either end do not overlap it.
+	assert.NoError(t, CheckReserved(rr, "dev", start.Add(-time.Hour), start))
+	assert.NoError(t, CheckReserved(rr, "dev", start.Add(time.Hour), start.Add(2*time.Hour)))
+
+	assert.ErrorIs(t, CheckReserved(rr, "dev", start.Add(30*time.Minute), start.Add(2*time.Hour)), ErrDeviceReserved)
+	assert.NoError(t, CheckReserved(rr, "qa", start.Add(30*time.Minute), start.Add(2*time.Hour)))
+}
+
+func TestCheckVersion(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+	d.Version = 3
+
+	assert.NoError(t, d.CheckVersion(AnyVersion))
+	assert.NoError(t, d.CheckVersion(3))
+	assert.Equal(t, &ErrVersionConflict{Expected: 2, Actual: 3}, d.CheckVersion(2))
+}
+
+func TestBrandSlug(t *testing.T) {
+	assert.Equal(t, "apple", BrandSlug("Apple"))
+	assert.Equal(t, "apple", BrandSlug("APPLE "))
+	assert.Equal(t, "hewlett-packard", BrandSlug("Hewlett  Packard"))
+	assert.Equal(t, "at-t", BrandSlug("AT&T"))
+	assert.Equal(t, "", BrandSlug(" -- "))
+}
+
+func TestLocationKindCheckParent(t *testing.T) {
+	site := &Location{Id: 1, Kind: Site}
+	building := &Location{Id: 2, ParentId: 1, Kind: Building}
+
+	assert.NoError(t, Site.CheckParent(nil))
+	assert.NoError(t, Building.CheckParent(site))
+	assert.NoError(t, Rack.CheckParent(building))
+
+	assert.ErrorIs(t, Building.CheckParent(nil), ErrInvalidLocation)
+	assert.ErrorIs(t, Site.CheckParent(building), ErrInvalidLocation)
+	assert.ErrorIs(t, Building.CheckParent(building), ErrInvalidLocation)
+	assert.ErrorIs(t, LocationKind("shelf").CheckParent(building), ErrInvalidLocation)
+}
+
+func TestTransfer(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	d := NewDevice("name01", "brand01")
+	d.Id = 1
+	d.OwnerId = 2
+
+	_, err := d.RequestTransfer(CreateTransfer{ToTeamId: 2}, "alice", now)
+	assert.ErrorIs(t, err, ErrInvalidTransfer)
+
+	tr, err := d.RequestTransfer(CreateTransfer{ToTeamId: 3}, "alice", now)
+	assert.NoError(t, err)
+	assert.Equal(t, &Transfer{DeviceId: 1, FromTeamId: 2, ToTeamId: 3, Status: TransferPending, RequestedBy: "alice", RequestedAt: now}, tr)
+
+	var ve *ErrValidation
+	assert.ErrorIs(t, tr.Resolve(TransferPending, TransferResolution{TeamId: 3}, "bob", now), ErrInvalidTransfer)
+	assert.ErrorIs(t, tr.Resolve(TransferAccepted, TransferResolution{TeamId: 2}, "alice", now), ErrWrongTransferTeam)
+	assert.ErrorAs(t, tr.Resolve(TransferRejected, TransferResolution{}, "alice", now), &ve)
+	assert.ErrorIs(t, tr.Resolve(TransferCancelled, TransferResolution{TeamId: 3}, "bob", now), ErrWrongTransferTeam)
+	assert.Equal(t, TransferPending, tr.Status)
+
+	assert.NoError(t, tr.Resolve(TransferAccepted, TransferResolution{TeamId: 3}, "bob", now))
+	assert.Equal(t, "bob", tr.ResolvedBy)
+	assert.ErrorIs(t, tr.Resolve(TransferCancelled, TransferResolution{TeamId: 2}, "alice", now), ErrTransferResolved)
+
+	// A device without an owner is offered by no team, so the transfer can
+	// only be rejected.
+	d.OwnerId = 0
+	tr, err = d.RequestTransfer(CreateTransfer{ToTeamId: 3}, "alice", now)
+	assert.NoError(t, err)
+	assert.ErrorAs(t, tr.Resolve(TransferCancelled, TransferResolution{}, "alice", now), &ve)
+	assert.ErrorIs(t, tr.Resolve(TransferCancelled, TransferResolution{TeamId: 3}, "bob", now), ErrWrongTransferTeam)
+	assert.NoError(t, tr.Resolve(TransferRejected, TransferResolution{TeamId: 3}, "bob", now))
+}
+
+func TestMaintenanceWindow(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	w := &MaintenanceWindow{Id: 1, DeviceId: 1, StartsAt: now, EndsAt: now.Add(time.Hour), Reason: "battery", Technician: "bob"}
+
+	d := NewDevice("name01", "brand01")
+	d.State = InUse
+	assert.Equal(t, &ErrInvalidTransition{From: InUse, To: Maintenance}, d.StartMaintenance(w, now))
+	assert.Nil(t, w.StartedAt)
+
+	d.State = Inactive
+	assert.NoError(t, d.StartMaintenance(w, now))
+	assert.Equal(t, Maintenance, d.State)
+	assert.Equal(t, Inactive, *w.PreviousState)
+	assert.ErrorIs(t, d.StartMaintenance(w, now), ErrMaintenanceStarted)
+
+	assert.NoError(t, d.EndMaintenance(w, now.Add(time.Hour)))
+	assert.Equal(t, Inactive, d.State)
+	assert.NotNil(t, w.EndedAt)
+}
+
+func TestCheckMaintenance(t *testing.T) {
+	start := time.Date(2024, time.March, 5, 9, 0, 0, 0, time.UTC)
+	ww := []MaintenanceWindow{{StartsAt: start, EndsAt: start.Add(time.Hour)}}
+
+	assert.NoError(t, CheckMaintenance(ww, start.Add(-time.Hour), start))
+	assert.ErrorIs(t, CheckMaintenance(ww, start.Add(-time.Hour), start.Add(time.Minute)), ErrDeviceInMaintenance)
+}
+
+func TestCreateMaintenanceValidate(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	valid := CreateMaintenance{StartsAt: now, EndsAt: now.Add(time.Hour), Reason: "battery", Technician: "bob"}
+	assert.NoError(t, valid.Validate(now))
+
+	noReason := valid
+	noReason.Reason = ""
+	assert.ErrorIs(t, noReason.Validate(now), ErrInvalidMaintenance)
+
+	past := valid
+	past.StartsAt, past.EndsAt = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	assert.ErrorIs(t, past.Validate(now), ErrInvalidMaintenance)
+}
+
+func TestDateJSON(t *testing.T) {
+	var l Lifecycle
+	err := json.Unmarshal([]byte(`{"purchase_date":"2024-03-15"}`), &l)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-15", l.PurchaseDate.String())
+	assert.Nil(t, l.WarrantyExpiresOn)
+
+	b, err := json.Marshal(l)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"purchase_date":"2024-03-15"}`, string(b))
+
+	err = json.Unmarshal([]byte(`{"purchase_date":"15/03/2024"}`), &l)
+	assert.Error(t, err)
+}
+
+func TestLifecycleValidateDates(t *testing.T) {
+	purchased, _ := ParseDate("2024-03-15")
+	before, _ := ParseDate("2024-01-01")
+	after, _ := ParseDate("2027-03-15")
+
+	assert.NoError(t, Lifecycle{}.ValidateDates())
+	assert.NoError(t, Lifecycle{PurchaseDate: &purchased, WarrantyExpiresOn: &after, EndOfLifeOn: &after}.ValidateDates())
+	assert.ErrorIs(t, Lifecycle{PurchaseDate: &purchased, WarrantyExpiresOn: &before}.ValidateDates(), ErrInvalidLifecycle)
+	assert.ErrorIs(t, Lifecycle{PurchaseDate: &purchased, EndOfLifeOn: &before}.ValidateDates(), ErrInvalidLifecycle)
+}
+
+func TestCreateGroupValidate(t *testing.T) {
+	f, err := CreateGroup{Name: "Android CI farm", Filter: "attr.os=android&selector=team=ci"}.Validate()
+	assert.NoError(t, err)
+	assert.Len(t, f.Attributes, 1)
+	assert.Len(t, f.Labels, 1)
+
+	_, err = CreateGroup{Name: "static"}.Validate()
+	assert.NoError(t, err)
+
+	_, err = CreateGroup{Name: " "}.Validate()
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+
+	_, err = CreateGroup{Name: "everything", Filter: "page=2"}.Validate()
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+
+	_, err = CreateGroup{Name: "broken", Filter: "attr.ram_gb>=lots"}.Validate()
+	assert.ErrorIs(t, err, ErrInvalidGroup)
+}
+
+func TestGroupAction(t *testing.T) {
+	inactive, unknown := Inactive, DeviceState(42)
+
+	assert.ErrorIs(t, GroupAction{}.Validate(), ErrInvalidGroupAction)
+	assert.ErrorIs(t, GroupAction{State: &unknown}.Validate(), ErrInvalidGroupAction)
+	assert.NoError(t, GroupAction{SetLabels: map[string]string{"team": "ci"}}.Validate())
+
+	a := GroupAction{State: &inactive}
+
+	d := NewDevice("name01", "brand01")
+	d.State = Available
+	assert.NoError(t, a.Apply(d))
+	assert.Equal(t, Inactive, d.State)
+
+	d.State = InUse
+	assert.ErrorIs(t, a.Apply(d), ErrDeviceInUse)
+	assert.Equal(t, InUse, d.State)
+}
+
+func TestGroupReportRollBack(t *testing.T) {
+	r := GroupReport{}
+	r.Add(1, &Device{Id: 1}, nil)
+	r.Add(2, nil, ErrDeviceInUse)
+	r.RollBack()
+
+	assert.True(t, r.RolledBack)
+	assert.Equal(t, 0, r.Applied)
+	assert.Equal(t, 1, r.Failed)
+	assert.False(t, r.Results[0].Applied)
+	assert.Nil(t, r.Results[0].Device)
+}
+
+func TestCheckCycle(t *testing.T) {
+	assert.NoError(t, CheckCycle(1, []int64{2, 3}))
+	assert.ErrorIs(t, CheckCycle(1, []int64{1}), ErrCompositionCycle)
+	assert.ErrorIs(t, CheckCycle(1, []int64{3, 2, 1}), ErrCompositionCycle)
+}
+
+func TestCheckParentState(t *testing.T) {
+	part := &Device{Id: 2, ParentId: 1, State: Available}
+
+	assert.NoError(t, part.CheckParentState(nil))
+	assert.NoError(t, part.CheckParentState(&Device{Id: 1, State: Available}))
+	assert.ErrorIs(t, part.CheckParentState(&Device{Id: 1, State: InUse}), ErrParentInUse)
+}
+
+func TestBuildTree(t *testing.T) {
+	dd := []Device{
+		{Id: 1, Name: "rig"},
+		{Id: 2, Name: "host", ParentId: 1},
+		{Id: 3, Name: "hub", ParentId: 1},
+		{Id: 4, Name: "phone", ParentId: 3},
+	}
+
+	n, err := BuildTree(1, dd)
+	assert.NoError(t, err)
+	assert.Equal(t, "rig", n.Name)
+	assert.Len(t, n.Children, 2)
+	assert.Equal(t, "hub", n.Children[1].Name)
+	assert.Equal(t, "phone", n.Children[1].Children[0].Name)
+	assert.Empty(t, n.Children[0].Children)
+
+	_, err = BuildTree(5, dd)
+	assert.ErrorIs(t, err, ErrNotExist)
+}
+
+func TestDeviceStateJSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		State   DeviceState           `json:"state"`
+		ByState map[DeviceState]int64 `json:"by_state"`
+	}{InUse, map[DeviceState]int64{Maintenance: 2}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"state":"in_use","by_state":{"maintenance":2}}`, string(b))
+
+	tests := []struct {
+		in   string
+		want DeviceState
+	}{
+		{`"available"`, Available},
+		{`"in_use"`, InUse},
+		{`"maintenance"`, Maintenance},
+		{`2`, Inactive},
+		{`"1"`, InUse},
+	}
+	for _, tt := range tests {
+		var ds DeviceState
+		assert.NoError(t, json.Unmarshal([]byte(tt.in), &ds), tt.in)
+		assert.Equal(t, tt.want, ds, tt.in)
+	}
+
+	for _, in := range []string{`"broken"`, `"InUse"`, `42`, `-1`, `true`, `1.5`} {
+		var ds DeviceState
+		assert.ErrorIs(t, json.Unmarshal([]byte(in), &ds), ErrInvalidState, in)
+	}
+
+	_, err = json.Marshal(DeviceState(42))
+	assert.Error(t, err)
+}
+
+func TestCreateDeviceValidate(t *testing.T) {
+	assert.NoError(t, CreateDevice{Name: "Pixel 8", Brand: "Google", SerialNumber: "SN-1/a"}.Validate())
+	assert.NoError(t, CreateDevice{Name: "Pixel 8", ModelId: 3}.Validate())
+
+	err := CreateDevice{
+		Name:       strings.Repeat("x", MaxNameLength+1),
+		Brand:      "Goo\x00gle",
+		AssetTag:   "tag #1",
+		State:      DeviceState(42),
+		Attributes: map[string]any{"ram gb": 8},
+		Lifecycle:  Lifecycle{PurchaseDate: &Date{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, EndOfLifeOn: &Date{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}},
+	}.Validate()
+
+	var verr *ErrValidation
+	assert.ErrorAs(t, err, &verr)
+
+	var fields []string
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"name", "brand", "asset_tag", "state", "attributes.ram gb", "end_of_life_on"}, fields)
+}
+
+func TestDecode(t *testing.T) {
+	var cd CreateDevice
+	assert.NoError(t, Decode(strings.NewReader(`{"Name":"Pixel 8","brand":"Google","purchase_date":"2024-05-01"}`), &cd))
+	assert.Equal(t, "Pixel 8", cd.Name)
+	assert.NotNil(t, cd.PurchaseDate)
+
+	var verr *ErrValidation
+	err := Decode(strings.NewReader(`{"name":"Pixel 8","serial":"SN-1","colour":"red"}`), &cd)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, []FieldError{
+		{Field: "colour", Message: "is not a known field"},
+		{Field: "serial", Message: "is not a known field"},
+	}, verr.Fields)
+
+	err = Decode(strings.NewReader(`{"name":7}`), &cd)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, "name", verr.Fields[0].Field)
+
+	err = Decode(strings.NewReader(`{"state":"broken"}`), &cd)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, "state", verr.Fields[0].Field)
+
+	// Every bad field is listed, whether it has the wrong type or fails
+	// validation.
+	err = Decode(strings.NewReader(`{"name":5,"brand":7}`), &CreateDevice{})
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, []FieldError{
+		{Field: "brand", Message: "must be a string"},
+		{Field: "name", Message: "must be a string"},
+	}, verr.Fields)
+
+	err = Decode(strings.NewReader(`{"name":"","brand":"","state":"broken"}`), &CreateDevice{})
+	assert.ErrorAs(t, err, &verr)
+	assert.ElementsMatch(t, []string{"state", "name", "brand"}, fieldNames(verr.Fields))
+
+	err = Decode(strings.NewReader(`{"name":"Pixel 8","brand":"Google","purchase_date":"yesterday"}`), &CreateDevice{})
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"purchase_date"}, fieldNames(verr.Fields))
+
+	// Maps take any key, each value decoded and validated on its own.
+	var patch LabelPatch
+	err = Decode(strings.NewReader(`{"team":"qa","env":null,"-bad":"x","owner":5}`), &patch)
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{"owner", "-bad"}, fieldNames(verr.Fields))
+	assert.Equal(t, "qa", *patch["team"])
+	assert.Contains(t, patch, "env")
+
+	err = Decode(strings.NewReader(`[]`), &cd)
+	assert.ErrorAs(t, err, &verr)
+
+	err = Decode(strings.NewReader(`{"name":`), &cd)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &verr))
+}
+
+func fieldNames(fields []FieldError) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Field
+	}
+	return names
+}
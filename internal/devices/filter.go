@@ -0,0 +1,249 @@
+package devices
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// DeviceFilter narrows a device listing. The zero value matches every
+// device. WarrantyExpiresBefore and EndOfLifeBefore only match devices that
+// have the date set.
+type DeviceFilter struct {
+	Attributes []AttributeFilter
+	Labels     Selector
+
+	WarrantyExpiresBefore *Date
+	EndOfLifeBefore       *Date
+}
+
+// IsEmpty reports whether the filter matches every device.
+func (f DeviceFilter) IsEmpty() bool {
+	return len(f.Attributes) == 0 && len(f.Labels) == 0 &&
+		f.WarrantyExpiresBefore == nil && f.EndOfLifeBefore == nil
+}
+
+// Matches reports whether d satisfies every condition of the filter.
+func (f DeviceFilter) Matches(d *Device) bool {
+	if !f.Labels.Matches(d.Labels) {
+		return false
+	}
+
+	if !dateBefore(d.WarrantyExpiresOn, f.WarrantyExpiresBefore) || !dateBefore(d.EndOfLifeOn, f.EndOfLifeBefore) {
+		return false
+	}
+
+	for _, af := range f.Attributes {
+		if !af.Matches(d.Attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+// dateBefore reports whether d is set and before limit. A nil limit matches
+// every date, including a missing one.
+func dateBefore(d, limit *Date) bool {
+	if limit == nil {
+		return true
+	}
+	return d != nil && d.Before(limit.Time)
+}
+
+// attributePrefix marks custom attribute filters in a query string, as in
+// attr.os=android&attr.ram_gb>=8.
+const attributePrefix = "attr."
+
+// selectorParam holds a label selector, as in selector=team=qa,env!=prod.
+const selectorParam = "selector="
+
+// Lifecycle date filters, as in warranty_expires_before=2027-01-01.
+const (
+	warrantyExpiresBeforeParam = "warranty_expires_before="
+	endOfLifeBeforeParam       = "end_of_life_before="
+)
+
+// ParseDeviceFilter reads a filter from a raw query string such as
+// "attr.os=android&selector=team=qa". Unknown parameters are ignored. The
+// query is split by hand because url.ParseQuery would treat the comparison
+// operators of attribute filters as part of the key.
+func ParseDeviceFilter(rawQuery string) (DeviceFilter, error) {
+	var f DeviceFilter
+
+	for _, part := range strings.Split(rawQuery, "&") {
+		expr, err := url.QueryUnescape(part)
+		if err != nil {
+			return DeviceFilter{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+
+		switch {
+		case strings.HasPrefix(expr, attributePrefix):
+			af, err := ParseAttributeFilter(strings.TrimPrefix(expr, attributePrefix))
+			if err != nil {
+				return DeviceFilter{}, err
+			}
+			f.Attributes = append(f.Attributes, af)
+
+		case strings.HasPrefix(expr, selectorParam):
+			sel, err := ParseSelector(strings.TrimPrefix(expr, selectorParam))
+			if err != nil {
+				return DeviceFilter{}, err
+			}
+			f.Labels = append(f.Labels, sel...)
+
+		case strings.HasPrefix(expr, warrantyExpiresBeforeParam):
+			f.WarrantyExpiresBefore, err = parseDateFilter(strings.TrimPrefix(expr, warrantyExpiresBeforeParam))
+			if err != nil {
+				return DeviceFilter{}, err
+			}
+
+		case strings.HasPrefix(expr, endOfLifeBeforeParam):
+			f.EndOfLifeBefore, err = parseDateFilter(strings.TrimPrefix(expr, endOfLifeBeforeParam))
+			if err != nil {
+				return DeviceFilter{}, err
+			}
+		}
+	}
+
+	return f, nil
+}
+
+func parseDateFilter(v string) (*Date, error) {
+	d, err := ParseDate(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return &d, nil
+}
+
+// AttributeOp is a comparison operator of an AttributeFilter.
+type AttributeOp string
+
+const (
+	AttrEq AttributeOp = "="
+	AttrNe AttributeOp = "!="
+	AttrGt AttributeOp = ">"
+	AttrGe AttributeOp = ">="
+	AttrLt AttributeOp = "<"
+	AttrLe AttributeOp = "<="
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// AttributeFilter compares a single custom attribute with a value.
+type AttributeFilter struct {
+	Key   string
+	Op    AttributeOp
+	Value any
+}
+
+// ParseAttributeFilter parses an expression such as "os=android" or
+// "ram_gb>=8". Numbers and booleans are compared by type; quote the value
+// ("version=\"8\"") to compare it as a string. Ordering operators only
+// accept numbers.
+func ParseAttributeFilter(expr string) (AttributeFilter, error) {
+	i := strings.IndexAny(expr, "=!<>")
+	if i < 0 {
+		return AttributeFilter{}, fmt.Errorf("%w: attribute expression %q has no operator", ErrInvalidFilter, expr)
+	}
+
+	key, rest := expr[:i], expr[i:]
+	if !attributeKeyPattern.MatchString(key) {
+		return AttributeFilter{}, fmt.Errorf("%w: attribute key %q", ErrInvalidFilter, key)
+	}
+
+	var op AttributeOp
+	switch {
+	case strings.HasPrefix(rest, ">="), strings.HasPrefix(rest, "<="), strings.HasPrefix(rest, "!="):
+		op = AttributeOp(rest[:2])
+	case rest[0] != '!':
+		op = AttributeOp(rest[:1])
+	default:
+		return AttributeFilter{}, fmt.Errorf("%w: attribute expression %q has no operator", ErrInvalidFilter, expr)
+	}
+	raw := strings.TrimPrefix(rest, string(op))
+
+	af := AttributeFilter{Key: key, Op: op, Value: attributeValue(raw)}
+	if _, isNumber := af.Value.(float64); !isNumber && op != AttrEq && op != AttrNe {
+		return AttributeFilter{}, fmt.Errorf("%w: %s needs a number, got %q", ErrInvalidFilter, op, raw)
+	}
+	return af, nil
+}
+
+func attributeValue(raw string) any {
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	if s, err := strconv.Unquote(raw); err == nil && strings.HasPrefix(raw, `"`) {
+		return s
+	}
+	return raw
+}
+
+// Matches reports whether attrs satisfy the filter. Missing attributes only
+// satisfy AttrNe.
+func (af AttributeFilter) Matches(attrs map[string]any) bool {
+	v, ok := attrs[af.Key]
+
+	switch af.Op {
+	case AttrEq:
+		return ok && attributeEqual(v, af.Value)
+	case AttrNe:
+		return !ok || !attributeEqual(v, af.Value)
+	}
+
+	n, isNumber := toFloat(v)
+	want, _ := af.Value.(float64)
+	if !ok || !isNumber {
+		return false
+	}
+
+	switch af.Op {
+	case AttrGt:
+		return n > want
+	case AttrGe:
+		return n >= want
+	case AttrLt:
+		return n < want
+	case AttrLe:
+		return n <= want
+	}
+	return false
+}
+
+func attributeEqual(v, want any) bool {
+	if n, ok := toFloat(v); ok {
+		w, isNumber := want.(float64)
+		return isNumber && n == w
+	}
+	return v == want
+}
+
+// toFloat accepts the number types produced by encoding/json and Go callers.
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
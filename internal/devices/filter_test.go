@@ -0,0 +1,251 @@
+package devices
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAttributeFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want AttributeFilter
+	}{
+		{"os=android", AttributeFilter{Key: "os", Op: AttrEq, Value: "android"}},
+		{"ram_gb>=8", AttributeFilter{Key: "ram_gb", Op: AttrGe, Value: 8.0}},
+		{"ram_gb<=8", AttributeFilter{Key: "ram_gb", Op: AttrLe, Value: 8.0}},
+		{"ram_gb>8", AttributeFilter{Key: "ram_gb", Op: AttrGt, Value: 8.0}},
+		{"ram_gb<8.5", AttributeFilter{Key: "ram_gb", Op: AttrLt, Value: 8.5}},
+		{"os!=ios", AttributeFilter{Key: "os", Op: AttrNe, Value: "ios"}},
+		{"rooted=true", AttributeFilter{Key: "rooted", Op: AttrEq, Value: true}},
+		{`version="8"`, AttributeFilter{Key: "version", Op: AttrEq, Value: "8"}},
+		{"note=a>b", AttributeFilter{Key: "note", Op: AttrEq, Value: "a>b"}},
+		{"cost-centre=", AttributeFilter{Key: "cost-centre", Op: AttrEq, Value: ""}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAttributeFilter(tt.expr)
+		if assert.NoError(t, err, tt.expr) {
+			assert.Equal(t, tt.want, got, tt.expr)
+		}
+	}
+}
+
+func TestParseAttributeFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"os",
+		"=android",
+		"os!android",
+		"ram gb=8",
+		"ram_gb>=eight",
+		"ram_gb<inf",
+		"os'; DROP TABLE devices; --=x",
+	} {
+		_, err := ParseAttributeFilter(expr)
+		assert.ErrorIs(t, err, ErrInvalidFilter, expr)
+	}
+}
+
+func TestAttributeFilterMatches(t *testing.T) {
+	attrs := map[string]any{"os": "android", "ram_gb": 8.0, "rooted": false, "version": "8"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"os=android", true},
+		{"os=ios", false},
+		{"os!=ios", true},
+		{"missing!=x", true},
+		{"missing=x", false},
+		{"ram_gb=8", true},
+		{"ram_gb>=8", true},
+		{"ram_gb>8", false},
+		{"ram_gb<16", true},
+		{"os>1", false},
+		{"rooted=false", true},
+		{"version=8", false},
+		{`version="8"`, true},
+	}
+
+	for _, tt := range tests {
+		af, err := ParseAttributeFilter(tt.expr)
+		if assert.NoError(t, err, tt.expr) {
+			assert.Equal(t, tt.want, af.Matches(attrs), tt.expr)
+		}
+	}
+}
+
+func TestDeviceFilterMatches(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+	d.Attributes = map[string]any{"os": "android", "ram_gb": 8}
+
+	assert.True(t, DeviceFilter{}.Matches(d))
+
+	os, _ := ParseAttributeFilter("os=android")
+	ram, _ := ParseAttributeFilter("ram_gb>=12")
+
+	assert.True(t, DeviceFilter{Attributes: []AttributeFilter{os}}.Matches(d))
+	assert.False(t, DeviceFilter{Attributes: []AttributeFilter{os, ram}}.Matches(d))
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		sel  string
+		want Selector
+	}{
+		{"", Selector{}},
+		{"   ", Selector{}},
+		{"team=qa", Selector{{Key: "team", Op: SelectEquals, Values: []string{"qa"}}}},
+		{"team==qa", Selector{{Key: "team", Op: SelectEquals, Values: []string{"qa"}}}},
+		{"team = qa", Selector{{Key: "team", Op: SelectEquals, Values: []string{"qa"}}}},
+		{"team=", Selector{{Key: "team", Op: SelectEquals, Values: []string{""}}}},
+		{"env!=prod", Selector{{Key: "env", Op: SelectNotEquals, Values: []string{"prod"}}}},
+		{"team=qa,env!=prod", Selector{
+			{Key: "team", Op: SelectEquals, Values: []string{"qa"}},
+			{Key: "env", Op: SelectNotEquals, Values: []string{"prod"}},
+		}},
+		{"tier in (web, db)", Selector{{Key: "tier", Op: SelectIn, Values: []string{"web", "db"}}}},
+		{"tier in(web)", Selector{{Key: "tier", Op: SelectIn, Values: []string{"web"}}}},
+		{"tier notin (web,db)", Selector{{Key: "tier", Op: SelectNotIn, Values: []string{"web", "db"}}}},
+		{"gpu", Selector{{Key: "gpu", Op: SelectExists}}},
+		{"!gpu", Selector{{Key: "gpu", Op: SelectDoesNotExist}}},
+		{" ! gpu ", Selector{{Key: "gpu", Op: SelectDoesNotExist}}},
+		{"example.com/team=qa", Selector{{Key: "example.com/team", Op: SelectEquals, Values: []string{"qa"}}}},
+		{"rack in (r1,r2),team=qa,!gpu,os", Selector{
+			{Key: "rack", Op: SelectIn, Values: []string{"r1", "r2"}},
+			{Key: "team", Op: SelectEquals, Values: []string{"qa"}},
+			{Key: "gpu", Op: SelectDoesNotExist},
+			{Key: "os", Op: SelectExists},
+		}},
+		{"in in (in)", Selector{{Key: "in", Op: SelectIn, Values: []string{"in"}}}},
+		{"notin=in", Selector{{Key: "notin", Op: SelectEquals, Values: []string{"in"}}}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelector(tt.sel)
+		if assert.NoError(t, err, tt.sel) {
+			assert.Equal(t, tt.want, got, tt.sel)
+		}
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, sel := range []string{
+		",",
+		"team=qa,",
+		",team=qa",
+		"team=qa,,env=prod",
+		"=qa",
+		"!",
+		"team=q a",
+		"team=qa!",
+		"team!qa",
+		"team in web",
+		"team in ()",
+		"team in ( , )",
+		"team in (web",
+		"team in web)",
+		"team in ((web))",
+		"team notin",
+		"team within (web)",
+		"-team=qa",
+		"team-=qa",
+		"Example.com/team=qa",
+		"/team=qa",
+		"team=" + strings.Repeat("a", 64),
+		"team='qa'",
+	} {
+		_, err := ParseSelector(sel)
+		assert.ErrorIs(t, err, ErrInvalidFilter, sel)
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"team": "qa", "env": "staging", "rack": "r12"}
+
+	tests := []struct {
+		sel  string
+		want bool
+	}{
+		{"", true},
+		{"team=qa", true},
+		{"team=dev", false},
+		{"team!=dev", true},
+		{"team!=qa", false},
+		{"owner!=bob", true},
+		{"env in (staging, prod)", true},
+		{"env in (prod)", false},
+		{"env notin (prod)", true},
+		{"env notin (staging)", false},
+		{"owner notin (bob)", true},
+		{"owner in (bob)", false},
+		{"rack", true},
+		{"gpu", false},
+		{"!gpu", true},
+		{"!rack", false},
+		{"team=qa,env!=prod,rack in (r11,r12),!gpu", true},
+		{"team=qa,env=prod", false},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.sel)
+		if assert.NoError(t, err, tt.sel) {
+			assert.Equal(t, tt.want, sel.Matches(labels), tt.sel)
+		}
+	}
+
+	sel, _ := ParseSelector("env!=prod,!gpu")
+	assert.True(t, sel.Matches(nil))
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.NoError(t, ValidateLabels(nil))
+	assert.NoError(t, ValidateLabels(map[string]string{
+		"team":             "qa",
+		"example.com/rack": "r12",
+		"empty":            "",
+		"a.b_c-d":          "A.b_c-9",
+	}))
+
+	for _, labels := range []map[string]string{
+		{"": "qa"},
+		{"team": "q a"},
+		{"team/": "qa"},
+		{"a/b/c": "qa"},
+		{"team": "-qa"},
+		{strings.Repeat("k", 64): "v"},
+	} {
+		assert.ErrorIs(t, ValidateLabels(labels), ErrInvalidLabel, labels)
+	}
+}
+
+func TestDeviceFilterMatches_Labels(t *testing.T) {
+	d := NewDevice("name01", "brand01")
+	d.Labels = map[string]string{"team": "qa"}
+	d.Attributes = map[string]any{"os": "android"}
+
+	sel, _ := ParseSelector("team=qa")
+	os, _ := ParseAttributeFilter("os=ios")
+
+	assert.True(t, DeviceFilter{Labels: sel}.Matches(d))
+	assert.False(t, DeviceFilter{Labels: sel, Attributes: []AttributeFilter{os}}.Matches(d))
+}
+
+func TestDeviceFilterMatches_WarrantyExpiresBefore(t *testing.T) {
+	limit, _ := ParseDate("2027-01-01")
+	early, _ := ParseDate("2026-06-30")
+	late, _ := ParseDate("2027-06-30")
+
+	d := NewDevice("name01", "brand01")
+	f := DeviceFilter{WarrantyExpiresBefore: &limit}
+
+	assert.False(t, f.Matches(d))
+
+	d.WarrantyExpiresOn = &early
+	assert.True(t, f.Matches(d))
+
+	d.WarrantyExpiresOn = &late
+	assert.False(t, f.Matches(d))
+}
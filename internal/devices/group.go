@@ -0,0 +1,155 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidGroup       = errors.New("invalid group")
+	ErrInvalidGroupAction = errors.New("group action needs a state, label or ownership change")
+	ErrGroupActionFailed  = errors.New("group action failed for some members and was rolled back")
+)
+
+// Group is a named fleet of devices, such as "Android CI farm". Its members
+// are the devices added to it by id and, when Filter is set, every device
+// matching the filter. Filter uses the query string syntax of the device
+// listing, as in "selector=team=qa&attr.os=android".
+type Group struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Filter      string    `json:"filter,omitempty"`
+	DeviceIds   []int64   `json:"device_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateGroup represents the model to create a group or change its name,
+// description and filter.
+type CreateGroup struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Filter      string `json:"filter,omitempty"`
+}
+
+// Validate checks the name and parses the filter, returning it. An empty
+// Filter gives an empty DeviceFilter, but a filter that is set has to
+// restrict the devices, otherwise every device would join the group.
+func (cg CreateGroup) Validate() (DeviceFilter, error) {
+	if strings.TrimSpace(cg.Name) == "" {
+		return DeviceFilter{}, fmt.Errorf("%w: a group needs a name", ErrInvalidGroup)
+	}
+
+	if cg.Filter == "" {
+		return DeviceFilter{}, nil
+	}
+
+	f, err := ParseDeviceFilter(cg.Filter)
+	if err != nil {
+		return DeviceFilter{}, fmt.Errorf("%w: %v", ErrInvalidGroup, err)
+	}
+	if f.IsEmpty() {
+		return DeviceFilter{}, fmt.Errorf("%w: filter %q matches every device", ErrInvalidGroup, cg.Filter)
+	}
+	return f, nil
+}
+
+// GroupMembers represents the model to add devices to a group.
+type GroupMembers struct {
+	DeviceIds []int64 `json:"device_ids"`
+}
+
+// GroupAction is a change applied to every member of a group in one
+// transaction. State moves each device through the state machine, the
+// label fields work as in a label update, and TransferToTeamId requests an
+// ownership transfer of each device that the team still has to accept.
+//
+// Members the change cannot be applied to, for example because they are in
+// use, are reported as failed and left as they were. With Atomic set, a
+// single failure rolls back the whole action instead.
+type GroupAction struct {
+	State            *DeviceState      `json:"state,omitempty"`
+	SetLabels        map[string]string `json:"set_labels,omitempty"`
+	RemoveLabels     []string          `json:"remove_labels,omitempty"`
+	TransferToTeamId int64             `json:"transfer_to_team_id,omitempty"`
+	Atomic           bool              `json:"atomic,omitempty"`
+}
+
+// Validate checks that the action changes something and that the new state
+// and labels are valid.
+func (a GroupAction) Validate() error {
+	if a.State == nil && len(a.SetLabels) == 0 && len(a.RemoveLabels) == 0 && a.TransferToTeamId == 0 {
+		return ErrInvalidGroupAction
+	}
+
+	if a.State != nil && !a.State.IsValid() {
+		return fmt.Errorf("%w: unknown state %s", ErrInvalidGroupAction, *a.State)
+	}
+
+	return ValidateLabels(a.SetLabels)
+}
+
+// ChangesDevice reports whether the action writes to the devices
+// themselves, as opposed to only requesting transfers.
+func (a GroupAction) ChangesDevice() bool {
+	return a.State != nil || len(a.SetLabels) > 0 || len(a.RemoveLabels) > 0
+}
+
+// Apply changes the state of d as requested. Devices in use are refused,
+// since they have to be checked in first. The labels are left to the
+// repository.
+func (a GroupAction) Apply(d *Device) error {
+	if a.State == nil || d.State == *a.State {
+		return nil
+	}
+
+	if d.IsDeviceInUse() {
+		return fmt.Errorf("%w%s", ErrDeviceInUse, d.heldBy())
+	}
+
+	return d.ChangeDeviceState(*a.State)
+}
+
+// GroupResult is the outcome of a group action for one member. Device is
+// the device after the change and Error why it was not applied.
+type GroupResult struct {
+	DeviceId int64   `json:"device_id"`
+	Applied  bool    `json:"applied"`
+	Error    string  `json:"error,omitempty"`
+	Device   *Device `json:"device,omitempty"`
+}
+
+// GroupReport lists the outcome of a group action for every member.
+// RolledBack is set when an atomic action failed and nothing was applied.
+type GroupReport struct {
+	GroupId    int64         `json:"group_id"`
+	Applied    int           `json:"applied"`
+	Failed     int           `json:"failed"`
+	RolledBack bool          `json:"rolled_back,omitempty"`
+	Results    []GroupResult `json:"results"`
+}
+
+// Add records the outcome for one member.
+func (r *GroupReport) Add(deviceId int64, d *Device, err error) {
+	if err != nil {
+		r.Failed++
+		r.Results = append(r.Results, GroupResult{DeviceId: deviceId, Error: err.Error()})
+		return
+	}
+
+	r.Applied++
+	r.Results = append(r.Results, GroupResult{DeviceId: deviceId, Applied: true, Device: d})
+}
+
+// RollBack marks every applied change as undone after an atomic action
+// failed.
+func (r *GroupReport) RollBack() {
+	r.RolledBack = true
+	r.Applied = 0
+	for i := range r.Results {
+		r.Results[i].Applied = false
+		r.Results[i].Device = nil
+	}
+}
@@ -0,0 +1,78 @@
+package devices
+
+import (
+	"context"
+	"time"
+)
+
+// HistoryAction names the kind of change recorded in a HistoryEntry.
+type HistoryAction string
+
+const (
+	HistoryCreated  HistoryAction = "create"
+	HistoryUpdated  HistoryAction = "update"
+	HistoryDeleted  HistoryAction = "delete"
+	HistoryRestored HistoryAction = "restore"
+	HistoryLabels   HistoryAction = "labels"
+	HistoryMoved    HistoryAction = "move"
+	HistoryAttached HistoryAction = "attach"
+
+	HistoryAssigned    HistoryAction = "assign"
+	HistoryTransferred HistoryAction = "transfer"
+
+	HistoryGroupAction HistoryAction = "group_action"
+
+	HistoryCheckedOut   HistoryAction = "checkout"
+	HistoryCheckedIn    HistoryAction = "checkin"
+	HistoryLeaseExpired HistoryAction = "lease_expired"
+
+	HistoryMaintenanceStarted HistoryAction = "maintenance_started"
+	HistoryMaintenanceEnded   HistoryAction = "maintenance_ended"
+)
+
+// HistoryEntry is a before/after record of a single change to a device.
+// Before is nil for creations.
+type HistoryEntry struct {
+	Id        int64         `json:"id"`
+	DeviceId  int64         `json:"device_id"`
+	Action    HistoryAction `json:"action"`
+	Actor     string        `json:"actor"`
+	Before    *Device       `json:"before"`
+	After     *Device       `json:"after"`
+	ChangedAt time.Time     `json:"changed_at"`
+}
+
+// HistoryFilter restricts a history query to entries changed in [From, To).
+// A zero time leaves that side of the range open.
+type HistoryFilter struct {
+	From time.Time
+	To   time.Time
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the name of whoever is making the
+// change, so repositories can record it in the device history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or an empty string.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying the id of the directory user making
+// the request, so repositories can check what the user may do.
+func WithUser(ctx context.Context, userId int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userId)
+}
+
+// UserFromContext returns the user id stored by WithUser, or zero.
+func UserFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(userKey{}).(int64)
+	return id
+}
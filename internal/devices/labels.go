@@ -0,0 +1,301 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidLabel = errors.New("invalid label")
+
+// Label keys and values follow the Kubernetes conventions: up to 63
+// alphanumeric characters, '-', '_' or '.', starting and ending with an
+// alphanumeric character. Keys may carry a DNS prefix ("example.com/team").
+var (
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern  = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+)
+
+// ValidateLabelKey checks a label key such as "team" or "example.com/team".
+func ValidateLabelKey(k string) error {
+	name := k
+	if prefix, n, ok := strings.Cut(k, "/"); ok {
+		if !labelPrefixPattern.MatchString(prefix) {
+			return fmt.Errorf("%w: key prefix %q", ErrInvalidLabel, prefix)
+		}
+		name = n
+	}
+
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: key %q", ErrInvalidLabel, k)
+	}
+	return nil
+}
+
+// ValidateLabelValue checks a label value. Empty values are allowed.
+func ValidateLabelValue(v string) error {
+	if !labelValuePattern.MatchString(v) {
+		return fmt.Errorf("%w: value %q", ErrInvalidLabel, v)
+	}
+	return nil
+}
+
+// ValidateLabels checks every key and value of labels.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if err := ValidateLabelKey(k); err != nil {
+			return err
+		}
+		if err := ValidateLabelValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LabelPatch is a JSON merge patch of the labels of a device: a value sets
+// the label and null removes it.
+type LabelPatch map[string]*string
+
+// Validate checks every key and every value that is set.
+func (p LabelPatch) Validate() error {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var v validator
+	for _, k := range keys {
+		if err := ValidateLabelKey(k); err != nil {
+			v.add(k, "%v", err)
+			continue
+		}
+		if p[k] == nil {
+			continue
+		}
+		if err := ValidateLabelValue(*p[k]); err != nil {
+			v.add(k, "%v", err)
+		}
+	}
+	return v.err()
+}
+
+// SelectorOp is the operator of a label selector requirement.
+type SelectorOp string
+
+const (
+	SelectEquals       SelectorOp = "="
+	SelectNotEquals    SelectorOp = "!="
+	SelectIn           SelectorOp = "in"
+	SelectNotIn        SelectorOp = "notin"
+	SelectExists       SelectorOp = "exists"
+	SelectDoesNotExist SelectorOp = "!"
+)
+
+// Requirement is a single condition of a label selector. Values holds one
+// value for SelectEquals and SelectNotEquals, the set for SelectIn and
+// SelectNotIn, and nothing for the existence checks.
+type Requirement struct {
+	Key    string
+	Op     SelectorOp
+	Values []string
+}
+
+// Selector is a conjunction of label requirements. The empty selector
+// matches every device.
+type Selector []Requirement
+
+// ParseSelector parses a Kubernetes style label selector such as
+// "team=qa,env!=prod,tier in (web, db),!gpu". Supported requirements are
+// key=value (or key==value), key!=value, key in (v1,v2), key notin (v1,v2),
+// key and !key.
+func ParseSelector(s string) (Selector, error) {
+	if strings.TrimSpace(s) == "" {
+		return Selector{}, nil
+	}
+
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := make(Selector, 0, len(parts))
+	for _, p := range parts {
+		r, err := parseRequirement(p)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitRequirements splits s on the commas that are not inside a value set.
+func splitRequirements(s string) ([]string, error) {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			if depth > 0 {
+				return nil, fmt.Errorf("%w: nested parenthesis in selector %q", ErrInvalidFilter, s)
+			}
+			depth++
+		case ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("%w: unbalanced parenthesis in selector %q", ErrInvalidFilter, s)
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("%w: unbalanced parenthesis in selector %q", ErrInvalidFilter, s)
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+func parseRequirement(p string) (Requirement, error) {
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return Requirement{}, fmt.Errorf("%w: empty selector requirement", ErrInvalidFilter)
+	}
+
+	if strings.HasPrefix(p, "!") {
+		key := strings.TrimSpace(p[1:])
+		if err := ValidateLabelKey(key); err != nil {
+			return Requirement{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		return Requirement{Key: key, Op: SelectDoesNotExist}, nil
+	}
+
+	end := strings.IndexFunc(p, func(r rune) bool {
+		return r == '=' || r == '!' || r == ' ' || r == '\t' || r == '('
+	})
+	if end < 0 {
+		end = len(p)
+	}
+
+	key, rest := p[:end], strings.TrimSpace(p[end:])
+	if err := ValidateLabelKey(key); err != nil {
+		return Requirement{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	r := Requirement{Key: key}
+	switch {
+	case rest == "":
+		r.Op = SelectExists
+		return r, nil
+	case strings.HasPrefix(rest, "!="):
+		r.Op, rest = SelectNotEquals, rest[2:]
+	case strings.HasPrefix(rest, "=="):
+		r.Op, rest = SelectEquals, rest[2:]
+	case strings.HasPrefix(rest, "="):
+		r.Op, rest = SelectEquals, rest[1:]
+	case hasKeyword(rest, "notin"):
+		r.Op, rest = SelectNotIn, rest[len("notin"):]
+	case hasKeyword(rest, "in"):
+		r.Op, rest = SelectIn, rest[len("in"):]
+	default:
+		return Requirement{}, fmt.Errorf("%w: unknown operator in %q", ErrInvalidFilter, p)
+	}
+
+	if r.Op == SelectIn || r.Op == SelectNotIn {
+		values, err := parseValueSet(strings.TrimSpace(rest))
+		if err != nil {
+			return Requirement{}, err
+		}
+		r.Values = values
+		return r, nil
+	}
+
+	v := strings.TrimSpace(rest)
+	if err := ValidateLabelValue(v); err != nil {
+		return Requirement{}, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	r.Values = []string{v}
+	return r, nil
+}
+
+// hasKeyword reports whether s starts with the word kw followed by a space or
+// an opening parenthesis.
+func hasKeyword(s, kw string) bool {
+	if !strings.HasPrefix(s, kw) {
+		return false
+	}
+	rest := s[len(kw):]
+	return strings.HasPrefix(rest, " ") || strings.HasPrefix(rest, "\t") || strings.HasPrefix(rest, "(")
+}
+
+func parseValueSet(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("%w: value set %q must be enclosed in parenthesis", ErrInvalidFilter, s)
+	}
+
+	var values []string
+	for _, v := range strings.Split(s[1:len(s)-1], ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if err := ValidateLabelValue(v); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: empty value set", ErrInvalidFilter)
+	}
+	return values, nil
+}
+
+// Matches reports whether labels satisfy every requirement of the selector.
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether labels satisfy the requirement. As in Kubernetes,
+// != and notin also match devices that do not have the label at all.
+func (r Requirement) Matches(labels map[string]string) bool {
+	v, ok := labels[r.Key]
+
+	switch r.Op {
+	case SelectEquals, SelectIn:
+		return ok && r.hasValue(v)
+	case SelectNotEquals, SelectNotIn:
+		return !ok || !r.hasValue(v)
+	case SelectExists:
+		return ok
+	case SelectDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+func (r Requirement) hasValue(v string) bool {
+	for _, want := range r.Values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
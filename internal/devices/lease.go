@@ -0,0 +1,82 @@
+package devices
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDeviceCheckedOut = errors.New("device is already checked out")
+	ErrNotCheckedOut    = errors.New("device is not checked out")
+	ErrInvalidCheckout  = errors.New("checkout needs a holder and an expected return time in the future")
+)
+
+// Lease records who holds a checked out device and until when. CascadedFrom
+// is the lease of the parent device when the device was checked out along
+// with it.
+type Lease struct {
+	Id               int64      `json:"id"`
+	DeviceId         int64      `json:"device_id"`
+	Holder           string     `json:"holder"`
+	CheckedOutAt     time.Time  `json:"checked_out_at"`
+	ExpectedReturnAt time.Time  `json:"expected_return_at"`
+	ReturnedAt       *time.Time `json:"returned_at,omitempty"`
+	CascadedFrom     int64      `json:"cascaded_from,omitempty"`
+}
+
+// Checkout represents the model to check out a device. With Cascade set,
+// every part of the device is checked out along with it and checked in
+// with it again.
+type Checkout struct {
+	Holder           string    `json:"holder"`
+	ExpectedReturnAt time.Time `json:"expected_return_at"`
+	Cascade          bool      `json:"cascade,omitempty"`
+}
+
+// Expired reports whether the lease should have been returned before now.
+func (l *Lease) Expired(now time.Time) bool {
+	return l.ReturnedAt == nil && l.ExpectedReturnAt.Before(now)
+}
+
+// CheckOut hands the device to c.Holder, moving it to InUse. The returned
+// lease is also attached to the device.
+func (d *Device) CheckOut(c Checkout, now time.Time) (*Lease, error) {
+	if c.Holder == "" || !c.ExpectedReturnAt.After(now) {
+		return nil, ErrInvalidCheckout
+	}
+
+	if d.Lease != nil || d.State == InUse {
+		return nil, ErrDeviceCheckedOut
+	}
+
+	err := d.ChangeDeviceState(InUse)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Lease = &Lease{
+		DeviceId:         d.Id,
+		Holder:           c.Holder,
+		CheckedOutAt:     now,
+		ExpectedReturnAt: c.ExpectedReturnAt,
+	}
+	return d.Lease, nil
+}
+
+// CheckIn ends the active lease, making the device Available again. The
+// returned lease is detached from the device.
+func (d *Device) CheckIn(now time.Time) (*Lease, error) {
+	if d.Lease == nil {
+		return nil, ErrNotCheckedOut
+	}
+
+	err := d.ChangeDeviceState(Available)
+	if err != nil {
+		return nil, err
+	}
+
+	l := d.Lease
+	l.ReturnedAt = &now
+	d.Lease = nil
+	return l, nil
+}
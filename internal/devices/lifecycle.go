@@ -0,0 +1,92 @@
+package devices
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidLifecycle = errors.New("invalid lifecycle dates")
+
+// dateLayout is the layout of calendar dates in the API.
+const dateLayout = time.DateOnly
+
+// Date is a calendar date without a time of day, written as "2006-01-02".
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date of t in its location.
+func NewDate(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses a date such as "2027-01-01".
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, err
+	}
+	return Date{t}, nil
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return fmt.Errorf("date must be a string such as \"2006-01-02\": %w", err)
+	}
+
+	*d, err = ParseDate(s)
+	return err
+}
+
+// Scan implements sql.Scanner for DATE columns.
+func (d *Date) Scan(src any) error {
+	t, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into a date", src)
+	}
+	*d = NewDate(t)
+	return nil
+}
+
+// Value implements driver.Valuer for DATE columns.
+func (d Date) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+// Lifecycle holds the purchase, warranty and end-of-life dates of a device.
+// Every date is optional.
+type Lifecycle struct {
+	PurchaseDate      *Date `json:"purchase_date,omitempty"`
+	WarrantyExpiresOn *Date `json:"warranty_expires_on,omitempty"`
+	EndOfLifeOn       *Date `json:"end_of_life_on,omitempty"`
+}
+
+// ValidateDates checks that the warranty does not expire and the device
+// does not reach its end of life before it was purchased.
+func (l Lifecycle) ValidateDates() error {
+	if l.PurchaseDate == nil {
+		return nil
+	}
+
+	if l.WarrantyExpiresOn != nil && l.WarrantyExpiresOn.Before(l.PurchaseDate.Time) {
+		return fmt.Errorf("%w: warranty expires on %s, before the purchase on %s", ErrInvalidLifecycle, l.WarrantyExpiresOn, l.PurchaseDate)
+	}
+
+	if l.EndOfLifeOn != nil && !l.EndOfLifeOn.After(l.PurchaseDate.Time) {
+		return fmt.Errorf("%w: end of life on %s is not after the purchase on %s", ErrInvalidLifecycle, l.EndOfLifeOn, l.PurchaseDate)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidLocation = errors.New("invalid location")
+	ErrUnknownLocation = errors.New("unknown location")
+	ErrLocationInUse   = errors.New("cannot delete location while it holds devices or other locations")
+)
+
+// LocationKind is the level of a location in the site → building → room →
+// rack tree.
+type LocationKind string
+
+const (
+	Site     LocationKind = "site"
+	Building LocationKind = "building"
+	Room     LocationKind = "room"
+	Rack     LocationKind = "rack"
+)
+
+var locationDepth = map[LocationKind]int{
+	Site:     0,
+	Building: 1,
+	Room:     2,
+	Rack:     3,
+}
+
+// IsValid reports whether k is one of the known location kinds.
+func (k LocationKind) IsValid() bool {
+	_, ok := locationDepth[k]
+	return ok
+}
+
+// CanContain reports whether a location of kind k may be the parent of a
+// location of kind child. Levels may be skipped, so a rack can sit directly
+// in a building, but never the other way round.
+func (k LocationKind) CanContain(child LocationKind) bool {
+	if !k.IsValid() || !child.IsValid() {
+		return false
+	}
+	return locationDepth[k] < locationDepth[child]
+}
+
+// CheckParent validates placing a location of kind k under parent, which is
+// nil for a root location.
+func (k LocationKind) CheckParent(parent *Location) error {
+	if !k.IsValid() {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidLocation, k)
+	}
+
+	if parent == nil {
+		if k != Site {
+			return fmt.Errorf("%w: a %s needs a parent location", ErrInvalidLocation, k)
+		}
+		return nil
+	}
+
+	if !parent.Kind.CanContain(k) {
+		return fmt.Errorf("%w: a %s cannot be placed in a %s", ErrInvalidLocation, k, parent.Kind)
+	}
+	return nil
+}
+
+// Location is a place where devices are kept. Sites are the roots of the
+// tree and have no parent.
+type Location struct {
+	Id        int64        `json:"id"`
+	ParentId  int64        `json:"parent_id,omitempty"`
+	Kind      LocationKind `json:"kind"`
+	Name      string       `json:"name"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// CreateLocation represents the model to add a location to the tree.
+type CreateLocation struct {
+	ParentId int64        `json:"parent_id,omitempty"`
+	Kind     LocationKind `json:"kind"`
+	Name     string       `json:"name"`
+}
+
+// UpdateLocation represents the model to rename a location or move it
+// under another parent. The kind of a location cannot change.
+type UpdateLocation struct {
+	ParentId int64  `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+}
+
+// Move represents the model to move a device to another location.
+type Move struct {
+	LocationId int64 `json:"location_id"`
+}
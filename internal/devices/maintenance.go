@@ -0,0 +1,104 @@
+package devices
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidMaintenance  = errors.New("maintenance needs a reason, a technician and a window that ends in the future after it starts")
+	ErrMaintenanceConflict = errors.New("maintenance window overlaps an existing maintenance window")
+	ErrMaintenanceStarted  = errors.New("maintenance window has already started")
+	ErrDeviceInMaintenance = errors.New("device is scheduled for maintenance")
+)
+
+// MaintenanceWindow schedules a device for maintenance during
+// [StartsAt, EndsAt). The device enters Maintenance when the window starts
+// and returns to PreviousState when it ends.
+type MaintenanceWindow struct {
+	Id            int64        `json:"id"`
+	DeviceId      int64        `json:"device_id"`
+	StartsAt      time.Time    `json:"starts_at"`
+	EndsAt        time.Time    `json:"ends_at"`
+	Reason        string       `json:"reason"`
+	Technician    string       `json:"technician"`
+	PreviousState *DeviceState `json:"previous_state,omitempty"`
+	StartedAt     *time.Time   `json:"started_at,omitempty"`
+	EndedAt       *time.Time   `json:"ended_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// CreateMaintenance represents the model to schedule a maintenance window.
+type CreateMaintenance struct {
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Reason     string    `json:"reason"`
+	Technician string    `json:"technician"`
+}
+
+// MaintenanceFilter restricts a maintenance query to windows overlapping
+// [From, To). A zero DeviceId matches every device and a zero time leaves
+// that side of the range open.
+type MaintenanceFilter struct {
+	DeviceId int64
+	From     time.Time
+	To       time.Time
+}
+
+// Validate checks the requested window against now.
+func (cm CreateMaintenance) Validate(now time.Time) error {
+	if cm.Reason == "" || cm.Technician == "" || !cm.StartsAt.Before(cm.EndsAt) || !cm.EndsAt.After(now) {
+		return ErrInvalidMaintenance
+	}
+	return nil
+}
+
+// Overlaps reports whether the window intersects [start, end).
+func (w *MaintenanceWindow) Overlaps(start, end time.Time) bool {
+	return w.StartsAt.Before(end) && start.Before(w.EndsAt)
+}
+
+// CheckMaintenance returns ErrDeviceInMaintenance if any of ww overlaps
+// [start, end).
+func CheckMaintenance(ww []MaintenanceWindow, start, end time.Time) error {
+	for i := range ww {
+		if ww[i].Overlaps(start, end) {
+			return ErrDeviceInMaintenance
+		}
+	}
+	return nil
+}
+
+// StartMaintenance moves the device to Maintenance for w, remembering the
+// state to return to. Devices in use cannot enter maintenance until they
+// are checked in.
+func (d *Device) StartMaintenance(w *MaintenanceWindow, now time.Time) error {
+	if w.StartedAt != nil {
+		return ErrMaintenanceStarted
+	}
+
+	prev := d.State
+	err := d.ChangeDeviceState(Maintenance)
+	if err != nil {
+		return err
+	}
+
+	w.PreviousState = &prev
+	w.StartedAt = &now
+	return nil
+}
+
+// EndMaintenance closes w and returns the device to the state it had before
+// the window started. A device taken out of Maintenance by hand meanwhile is
+// left as is.
+func (d *Device) EndMaintenance(w *MaintenanceWindow, now time.Time) error {
+	if d.State == Maintenance && w.PreviousState != nil {
+		err := d.ChangeDeviceState(*w.PreviousState)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.EndedAt = &now
+	return nil
+}
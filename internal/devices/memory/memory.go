@@ -0,0 +1,404 @@
+// Package memory provides an in-memory devices.Repository for local
+// development and tests. It follows the semantics of the postgres backend
+// but keeps nothing once the process exits.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"devices_api/internal/devices"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type service struct {
+	mu sync.RWMutex
+
+	// devices holds every device by id, including those in the trash.
+	devices map[int64]*devices.Device
+	brands  map[int64]*devices.Brand
+
+	lastDeviceId int64
+	lastBrandId  int64
+}
+
+// NewRepository returns an empty repository. It is safe for concurrent use.
+func NewRepository() devices.Repository {
+	return &service{
+		devices: map[int64]*devices.Device{},
+		brands:  map[int64]*devices.Brand{},
+	}
+}
+
+// now returns the current time at the precision Postgres stores.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// clone returns a copy of d that shares no maps or pointers with it, so
+// callers cannot change stored devices behind the lock.
+func clone(d *devices.Device) *devices.Device {
+	c := *d
+	c.Attributes = maps.Clone(d.Attributes)
+	c.Labels = maps.Clone(d.Labels)
+	if d.Lease != nil {
+		l := *d.Lease
+		c.Lease = &l
+	}
+	if d.DeletedAt != nil {
+		t := *d.DeletedAt
+		c.DeletedAt = &t
+	}
+	c.Lifecycle = cloneLifecycle(d.Lifecycle)
+	return &c
+}
+
+func cloneLifecycle(l devices.Lifecycle) devices.Lifecycle {
+	date := func(d *devices.Date) *devices.Date {
+		if d == nil {
+			return nil
+		}
+		c := *d
+		return &c
+	}
+	return devices.Lifecycle{
+		PurchaseDate:      date(l.PurchaseDate),
+		WarrantyExpiresOn: date(l.WarrantyExpiresOn),
+		EndOfLifeOn:       date(l.EndOfLifeOn),
+	}
+}
+
+// get returns the stored device id unless it is in the trash.
+func (s *service) get(id int64) (*devices.Device, error) {
+	d, ok := s.devices[id]
+	if !ok || d.DeletedAt != nil {
+		return nil, devices.ErrNotExist
+	}
+	return d, nil
+}
+
+// list returns copies of the devices outside the trash that match, ordered
+// by id.
+func (s *service) list(match func(d *devices.Device) bool) []devices.Device {
+	dd := []devices.Device{}
+	for _, d := range s.devices {
+		if d.DeletedAt == nil && match(d) {
+			dd = append(dd, *clone(d))
+		}
+	}
+	sort.Slice(dd, func(i, j int) bool { return dd[i].Id < dd[j].Id })
+	return dd
+}
+
+// resolveBrand looks a brand up by id, or by the slug of its name, adding
+// unknown names to the catalog. Models are not kept, so any modelId is
+// unknown.
+func (s *service) resolveBrand(id int64, name string, modelId int64) (*devices.Brand, error) {
+	if modelId != 0 {
+		return nil, devices.ErrUnknownModel
+	}
+
+	if id != 0 {
+		b, ok := s.brands[id]
+		if !ok {
+			return nil, devices.ErrUnknownBrand
+		}
+		return b, nil
+	}
+
+	slug := devices.BrandSlug(name)
+	if slug == "" {
+		return nil, devices.ErrUnknownBrand
+	}
+
+	for _, b := range s.brands {
+		if b.Slug == slug {
+			return b, nil
+		}
+	}
+
+	s.lastBrandId++
+	b := &devices.Brand{Id: s.lastBrandId, Name: strings.TrimSpace(name), Slug: slug, Aliases: []string{}, CreatedAt: now()}
+	s.brands[b.Id] = b
+	return b, nil
+}
+
+// checkUnique returns an *ErrDuplicateField if another device, not in the
+// trash, has the serial number or asset tag of d.
+func (s *service) checkUnique(d *devices.Device) error {
+	for _, o := range s.devices {
+		if o.Id == d.Id || o.DeletedAt != nil {
+			continue
+		}
+		if d.SerialNumber != "" && o.SerialNumber == d.SerialNumber {
+			return &devices.ErrDuplicateField{Field: "serial_number", Value: d.SerialNumber}
+		}
+		if d.AssetTag != "" && o.AssetTag == d.AssetTag {
+			return &devices.ErrDuplicateField{Field: "asset_tag", Value: d.AssetTag}
+		}
+	}
+	return nil
+}
+
+// checkParentState refuses changes to d while the assembly it is part of
+// is in use.
+func (s *service) checkParentState(d *devices.Device) error {
+	if d.ParentId == 0 {
+		return nil
+	}
+
+	// A parent in the trash no longer holds its parts.
+	p, err := s.get(d.ParentId)
+	if err != nil {
+		return nil
+	}
+	return d.CheckParentState(p)
+}
+
+func (s *service) Create(ctx context.Context, cd devices.CreateDevice) (*devices.Device, error) {
+	nd := devices.NewDevice(cd.Name, cd.Brand)
+	nd.SerialNumber = strings.TrimSpace(cd.SerialNumber)
+	nd.AssetTag = strings.TrimSpace(cd.AssetTag)
+	err := nd.ChangeDeviceState(cd.State)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = devices.ValidateLabels(cd.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = cd.ValidateDates()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := s.resolveBrand(cd.BrandId, cd.Brand, cd.ModelId)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = s.checkUnique(nd)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	s.lastDeviceId++
+	nd.Id = s.lastDeviceId
+	nd.Brand = b.Name
+	nd.BrandId = b.Id
+	nd.LocationId = cd.LocationId
+	nd.OwnerId = cd.OwnerId
+	nd.AssigneeId = cd.AssigneeId
+	nd.Attributes = maps.Clone(cd.Attributes)
+	nd.Labels = maps.Clone(cd.Labels)
+	nd.Lifecycle = cloneLifecycle(cd.Lifecycle)
+	nd.CreatedAt = now()
+	nd.Version = 1
+	s.devices[nd.Id] = nd
+
+	return clone(nd), nil
+}
+
+func (s *service) GetById(ctx context.Context, id int64) (*devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return clone(d), nil
+}
+
+// GetByBrand lists the devices of a brand given by id, name or slug.
+func (s *service) GetByBrand(ctx context.Context, brand string) ([]devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	brand = strings.TrimSpace(brand)
+	slug := devices.BrandSlug(brand)
+	return s.list(func(d *devices.Device) bool {
+		b := s.brands[d.BrandId]
+		return strconv.FormatInt(b.Id, 10) == brand || b.Slug == slug
+	}), nil
+}
+
+// GetByAssetTag looks a device up by the asset tag printed on its label.
+func (s *service) GetByAssetTag(ctx context.Context, tag string) (*devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag = strings.TrimSpace(tag)
+	dd := s.list(func(d *devices.Device) bool { return d.AssetTag == tag })
+	if len(dd) == 0 {
+		return nil, devices.ErrNotExist
+	}
+	return &dd[0], nil
+}
+
+func (s *service) GetByState(ctx context.Context, state devices.DeviceState) ([]devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(func(d *devices.Device) bool { return d.State == state }), nil
+}
+
+func (s *service) All(ctx context.Context) ([]devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(func(d *devices.Device) bool { return true }), nil
+}
+
+// Find lists the devices matching f.
+func (s *service) Find(ctx context.Context, f devices.DeviceFilter) ([]devices.Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list(f.Matches), nil
+}
+
+// Update applies the name, brand, identifiers, lifecycle dates, state,
+// attributes and labels of d to the stored device, with the same checks as
+// the postgres backend. The location, owner, assignee and parent are left
+// as is.
+func (s *service) Update(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.get(d.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	cur := clone(stored)
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.Name != d.Name {
+		err = cur.ChangeDeviceName(d.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := s.resolveBrand(d.BrandId, d.Brand, d.ModelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.BrandId != b.Id {
+		err = cur.ChangeDeviceBrand(b.Name)
+		if err != nil {
+			return nil, err
+		}
+		cur.BrandId = b.Id
+	}
+	cur.SerialNumber = strings.TrimSpace(d.SerialNumber)
+	cur.AssetTag = strings.TrimSpace(d.AssetTag)
+
+	if cur.State != d.State {
+		err = s.checkParentState(cur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cur.ChangeDeviceState(d.State)
+	if err != nil {
+		return nil, err
+	}
+
+	err = devices.ValidateLabels(d.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.ValidateDates()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkUnique(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	cur.Lifecycle = cloneLifecycle(d.Lifecycle)
+	cur.Attributes = maps.Clone(d.Attributes)
+	cur.Labels = maps.Clone(d.Labels)
+	cur.Version++
+	s.devices[cur.Id] = cur
+
+	return driver.RowsAffected(1), nil
+}
+
+// Delete moves the device to the trash. The in-use guard is checked against
+// the stored device, not d. Parts of an in-use device and devices that
+// still have parts cannot be deleted.
+func (s *service) Delete(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, err := s.get(d.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.IsDeviceInUse() {
+		return nil, devices.ErrDeviceInUse
+	}
+
+	err = s.checkParentState(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, o := range s.devices {
+		if o.ParentId == cur.Id && o.DeletedAt == nil {
+			return nil, devices.ErrDeviceHasParts
+		}
+	}
+
+	deleted := clone(cur)
+	t := now()
+	deleted.DeletedAt = &t
+	deleted.Version++
+	s.devices[deleted.Id] = deleted
+
+	return driver.RowsAffected(1), nil
+}
+
+// Health reports the number of stored devices.
+func (s *service) Health() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]string{
+		"status":  "up",
+		"message": "It's healthy",
+		"backend": "memory",
+		"devices": strconv.Itoa(len(s.devices)),
+	}
+}
+
+func (s *service) Close() error {
+	return nil
+}
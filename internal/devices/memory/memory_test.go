@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/devices/repotest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) devices.Repository {
+		return NewRepository()
+	})
+}
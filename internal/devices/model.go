@@ -0,0 +1,41 @@
+package devices
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownModel = errors.New("unknown model for the device brand")
+	ErrInvalidModel = errors.New("model needs a name")
+	ErrModelInUse   = errors.New("cannot delete model while devices use it")
+)
+
+// Model is a product line of a brand, such as "Pixel 8" of "Google". Specs
+// shared by every device of the model, like the CPU, the RAM or the form
+// factor, are kept on the model instead of on each device.
+type Model struct {
+	Id        int64          `json:"id"`
+	BrandId   int64          `json:"brand_id"`
+	Brand     string         `json:"brand"`
+	Name      string         `json:"name"`
+	Specs     map[string]any `json:"specs,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CreateModel represents the model to add a model to a brand.
+type CreateModel struct {
+	Name  string         `json:"name"`
+	Specs map[string]any `json:"specs,omitempty"`
+}
+
+// ModelInventory counts the devices of a model. Trashed devices are not
+// counted.
+type ModelInventory struct {
+	ModelId int64                 `json:"model_id"`
+	Model   string                `json:"model"`
+	BrandId int64                 `json:"brand_id"`
+	Brand   string                `json:"brand"`
+	Total   int64                 `json:"total"`
+	ByState map[DeviceState]int64 `json:"by_state"`
+}
@@ -0,0 +1,167 @@
+package devices
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidUser       = errors.New("user needs a name")
+	ErrInvalidTeam       = errors.New("team needs a name")
+	ErrUnknownUser       = errors.New("unknown user")
+	ErrUnknownTeam       = errors.New("unknown team")
+	ErrInvalidTransfer   = errors.New("transfer needs a target team other than the current owner")
+	ErrTransferPending   = errors.New("device already has a pending ownership transfer")
+	ErrTransferResolved  = errors.New("ownership transfer is no longer pending")
+	ErrWrongTransferTeam = errors.New("only the receiving team may accept or reject a transfer and only the offering team may cancel it")
+	ErrNotTeamMember     = errors.New("acting user is not a member of the team")
+)
+
+// User is a person devices can be assigned to.
+type User struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateUser represents the model to create a new user.
+type CreateUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Team is a group of people that owns devices.
+type Team struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateTeam represents the model to create a new team.
+type CreateTeam struct {
+	Name string `json:"name"`
+}
+
+// Membership represents the model to add a user to a team.
+type Membership struct {
+	UserId int64 `json:"user_id"`
+}
+
+// Assignment represents the model to assign a device to a user. A zero
+// UserId clears the assignee.
+type Assignment struct {
+	UserId int64 `json:"user_id"`
+}
+
+// TransferStatus is the state of an ownership transfer. Transfers start
+// pending and are resolved exactly once.
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferAccepted  TransferStatus = "accepted"
+	TransferRejected  TransferStatus = "rejected"
+	TransferCancelled TransferStatus = "cancelled"
+)
+
+// IsValid reports whether s is one of the known transfer statuses.
+func (s TransferStatus) IsValid() bool {
+	switch s {
+	case TransferPending, TransferAccepted, TransferRejected, TransferCancelled:
+		return true
+	}
+	return false
+}
+
+// Transfer hands the ownership of a device from one team to another. It only
+// takes effect once the receiving team accepts it. FromTeamId is zero for
+// devices that had no owner.
+type Transfer struct {
+	Id          int64          `json:"id"`
+	DeviceId    int64          `json:"device_id"`
+	FromTeamId  int64          `json:"from_team_id,omitempty"`
+	ToTeamId    int64          `json:"to_team_id"`
+	Status      TransferStatus `json:"status"`
+	RequestedBy string         `json:"requested_by"`
+	RequestedAt time.Time      `json:"requested_at"`
+	ResolvedBy  string         `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time     `json:"resolved_at,omitempty"`
+}
+
+// CreateTransfer represents the model to offer a device to another team.
+type CreateTransfer struct {
+	ToTeamId int64 `json:"to_team_id"`
+}
+
+// TransferResolution represents the model to resolve a transfer. TeamId is
+// the team acting on it: the receiving team accepts or rejects a transfer,
+// the offering team cancels it. The acting user must be a member of the
+// team.
+type TransferResolution struct {
+	TeamId int64 `json:"team_id"`
+}
+
+// Validate checks that the acting team is set.
+func (tr TransferResolution) Validate() error {
+	var v validator
+	if tr.TeamId <= 0 {
+		v.add("team_id", "is required")
+	}
+	return v.err()
+}
+
+// TransferFilter restricts a transfer query. TeamId matches transfers on
+// either side, so a team sees both what it offers and what it is offered.
+// Zero values match everything.
+type TransferFilter struct {
+	DeviceId int64
+	TeamId   int64
+	Status   TransferStatus
+}
+
+// RequestTransfer starts a transfer of the device to ct.ToTeamId.
+func (d *Device) RequestTransfer(ct CreateTransfer, by string, now time.Time) (*Transfer, error) {
+	if ct.ToTeamId == 0 || ct.ToTeamId == d.OwnerId {
+		return nil, ErrInvalidTransfer
+	}
+
+	return &Transfer{
+		DeviceId:    d.Id,
+		FromTeamId:  d.OwnerId,
+		ToTeamId:    ct.ToTeamId,
+		Status:      TransferPending,
+		RequestedBy: by,
+		RequestedAt: now,
+	}, nil
+}
+
+// Resolve moves a pending transfer to status, which must be one of the
+// final statuses, on behalf of the team in tr. Only the receiving team may
+// accept or reject the transfer and only the offering team may cancel it,
+// so the transfer of a device without an owner can only be rejected.
+func (t *Transfer) Resolve(status TransferStatus, tr TransferResolution, by string, now time.Time) error {
+	if !status.IsValid() || status == TransferPending {
+		return ErrInvalidTransfer
+	}
+	err := tr.Validate()
+	if err != nil {
+		return err
+	}
+	if t.Status != TransferPending {
+		return ErrTransferResolved
+	}
+
+	team := t.ToTeamId
+	if status == TransferCancelled {
+		team = t.FromTeamId
+	}
+	if tr.TeamId != team {
+		return ErrWrongTransferTeam
+	}
+
+	t.Status = status
+	t.ResolvedBy = by
+	t.ResolvedAt = &now
+	return nil
+}
@@ -0,0 +1,353 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// resolveBrandIds selects the brand matching $1 as an id, or $2 as a slug or
+// alias slug.
+const resolveBrandIds = `SELECT id FROM brands WHERE id::text = $1 OR slug = $2
+UNION
+SELECT brand_id FROM brand_aliases WHERE slug = $2`
+
+const selectBrands = `SELECT b.id, b.name, b.slug, b.created_at,
+  COALESCE((SELECT jsonb_agg(a.slug ORDER BY a.slug) FROM brand_aliases a WHERE a.brand_id = b.id), '[]')
+FROM brands b
+`
+
+const getBrandById = selectBrands + `WHERE b.id = $1`
+
+const getBrandByIdOrSlug = selectBrands + `WHERE b.id IN (` + resolveBrandIds + `) LIMIT 1`
+
+const getBrandBySlug = selectBrands + `WHERE b.slug = $1 OR b.id IN (SELECT brand_id FROM brand_aliases WHERE slug = $1) LIMIT 1`
+
+const getAllBrands = selectBrands + `ORDER BY b.name`
+
+func scanBrand(row rowScanner) (*devices.Brand, error) {
+	var (
+		b       devices.Brand
+		aliases []byte
+	)
+
+	err := row.Scan(&b.Id, &b.Name, &b.Slug, &b.CreatedAt, &aliases)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(aliases, &b.Aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+const insertBrand = `INSERT INTO brands (name, slug, created_at) VALUES ($1, $2, NOW())
+ON CONFLICT (slug) DO NOTHING`
+
+// resolveBrand finds the brand for a device write. A non-zero id must exist;
+// otherwise name is looked up by slug and alias, and added to the catalog if
+// it is unknown so clients sending free-text brands keep working.
+func resolveBrand(ctx context.Context, tx *sql.Tx, id int64, name string) (*devices.Brand, error) {
+	if id != 0 {
+		b, err := scanBrand(tx.QueryRowContext(ctx, getBrandById, id))
+		if errors.Is(err, devices.ErrNotExist) {
+			return nil, devices.ErrUnknownBrand
+		}
+		return b, err
+	}
+
+	slug := devices.BrandSlug(name)
+	if slug == "" {
+		return nil, devices.ErrUnknownBrand
+	}
+
+	b, err := scanBrand(tx.QueryRowContext(ctx, getBrandBySlug, slug))
+	if !errors.Is(err, devices.ErrNotExist) {
+		return b, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertBrand, strings.TrimSpace(name), slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBrand(tx.QueryRowContext(ctx, getBrandBySlug, slug))
+}
+
+const slugTaken = `SELECT EXISTS (SELECT 1 FROM brands WHERE slug = $1 AND id <> $2)
+  OR EXISTS (SELECT 1 FROM brand_aliases WHERE slug = $1 AND brand_id <> $2)`
+
+// checkSlugFree returns ErrDuplicate if slug already names or aliases a
+// brand other than brandId.
+func checkSlugFree(ctx context.Context, tx *sql.Tx, slug string, brandId int64) error {
+	var taken bool
+	err := tx.QueryRowContext(ctx, slugTaken, slug, brandId).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return devices.ErrDuplicate
+	}
+	return nil
+}
+
+const createBrand = `INSERT INTO brands (name, slug, created_at) VALUES ($1, $2, NOW()) RETURNING id`
+
+const insertBrandAlias = `INSERT INTO brand_aliases (slug, brand_id) VALUES ($1, $2)`
+
+// addBrandAlias leaves an existing alias alone, so that a repeated alias
+// does not abort the transaction with a unique violation.
+const addBrandAlias = insertBrandAlias + ` ON CONFLICT (slug) DO NOTHING`
+
+func (s *service) CreateBrand(ctx context.Context, cb devices.CreateBrand) (*devices.Brand, error) {
+	name := strings.TrimSpace(cb.Name)
+	slug := devices.BrandSlug(name)
+	if slug == "" {
+		return nil, devices.ErrInvalidBrand
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = checkSlugFree(ctx, tx, slug, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, createBrand, name, slug).Scan(&id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range cb.Aliases {
+		err = addAlias(ctx, tx, id, slug, a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := scanBrand(tx.QueryRowContext(ctx, getBrandById, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// addAlias adds alias to the brand brandId with slug brandSlug. Adding an
+// alias the brand already has does nothing; an alias repeating the slug of
+// the brand itself, or naming another brand, is a duplicate.
+func addAlias(ctx context.Context, tx *sql.Tx, brandId int64, brandSlug, alias string) error {
+	slug := devices.BrandSlug(alias)
+	if slug == "" {
+		return devices.ErrInvalidBrand
+	}
+	if slug == brandSlug {
+		return devices.ErrDuplicate
+	}
+
+	err := checkSlugFree(ctx, tx, slug, brandId)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, addBrandAlias, slug, brandId)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+	// Either already an alias of this brand, or taken by another brand
+	// since it was checked.
+	return checkSlugFree(ctx, tx, slug, brandId)
+}
+
+func (s *service) GetBrand(ctx context.Context, b string) (*devices.Brand, error) {
+	return scanBrand(s.db.QueryRowContext(ctx, getBrandByIdOrSlug, strings.TrimSpace(b), devices.BrandSlug(b)))
+}
+
+func (s *service) Brands(ctx context.Context) ([]devices.Brand, error) {
+	rows, err := s.db.QueryContext(ctx, getAllBrands)
+	if err != nil {
+		return []devices.Brand{}, err
+	}
+	defer rows.Close()
+
+	bb := []devices.Brand{}
+	for rows.Next() {
+		b, err := scanBrand(rows)
+		if err != nil {
+			return []devices.Brand{}, err
+		}
+		bb = append(bb, *b)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Brand{}, err
+	}
+
+	return bb, nil
+}
+
+const getBrandForUpdate = selectBrands + `WHERE b.id = $1 FOR UPDATE OF b`
+
+const renameBrand = `UPDATE brands SET name = $1, slug = $2 WHERE id = $3`
+
+const deleteBrandAlias = `DELETE FROM brand_aliases WHERE brand_id = $1 AND slug = $2`
+
+// RenameBrand changes the canonical name of a brand, which every device of
+// the brand picks up through its brand_id. The old slug becomes an alias so
+// lookups by the previous name keep working.
+func (s *service) RenameBrand(ctx context.Context, id int64, name string) (*devices.Brand, error) {
+	name = strings.TrimSpace(name)
+	slug := devices.BrandSlug(name)
+	if slug == "" {
+		return nil, devices.ErrInvalidBrand
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanBrand(tx.QueryRowContext(ctx, getBrandForUpdate, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if slug != cur.Slug {
+		err = checkSlugFree(ctx, tx, slug, id)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, deleteBrandAlias, id, slug)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, renameBrand, name, slug, id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if slug != cur.Slug {
+		_, err = tx.ExecContext(ctx, insertBrandAlias, cur.Slug, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := scanBrand(tx.QueryRowContext(ctx, getBrandById, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+const deleteBrand = `DELETE FROM brands WHERE id = $1`
+
+// DeleteBrand removes a brand and its aliases. Brands still referenced by a
+// device, including devices in the trash, cannot be deleted.
+func (s *service) DeleteBrand(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, deleteBrand, id)
+	if pgErrorCode(err) == foreignKeyViolation {
+		return devices.ErrBrandInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
+
+func (s *service) AddBrandAlias(ctx context.Context, id int64, alias string) (*devices.Brand, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanBrand(tx.QueryRowContext(ctx, getBrandForUpdate, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = addAlias(ctx, tx, id, cur.Slug, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := scanBrand(tx.QueryRowContext(ctx, getBrandById, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (s *service) RemoveBrandAlias(ctx context.Context, id int64, alias string) (*devices.Brand, error) {
+	result, err := s.db.ExecContext(ctx, deleteBrandAlias, id, devices.BrandSlug(alias))
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, devices.ErrNotExist
+	}
+
+	return scanBrand(s.db.QueryRowContext(ctx, getBrandById, id))
+}
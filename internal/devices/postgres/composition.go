@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"fmt"
+)
+
+// deviceSubtree selects the device $1 and every part below it.
+const deviceSubtree = `WITH RECURSIVE subtree AS (
+  SELECT id FROM devices WHERE id = $1
+  UNION
+  SELECT d.id FROM devices d JOIN subtree s ON d.parent_id = s.id
+)
+`
+
+const getDeviceTree = deviceSubtree + selectDevices + `WHERE d.deleted_at IS NULL AND d.id IN (SELECT id FROM subtree) ORDER BY d.id`
+
+const getPartIds = deviceSubtree + `SELECT d.id FROM devices d JOIN subtree s ON s.id = d.id
+WHERE d.id <> $1 AND d.deleted_at IS NULL
+ORDER BY d.id`
+
+// getAncestorIds selects the device $1 and every device it is part of.
+const getAncestorIds = `WITH RECURSIVE ancestors AS (
+  SELECT id, parent_id FROM devices WHERE id = $1
+  UNION
+  SELECT d.id, d.parent_id FROM devices d JOIN ancestors a ON d.id = a.parent_id
+)
+SELECT id FROM ancestors`
+
+const hasParts = `SELECT EXISTS (SELECT 1 FROM devices WHERE parent_id = $1 AND deleted_at IS NULL)`
+
+// compositionLockKey is the advisory lock serialising parent changes. Two
+// concurrent attaches could otherwise each pass the cycle check and close
+// a cycle between them.
+const compositionLockKey = 0x64657669636573
+
+const lockComposition = `SELECT pg_advisory_xact_lock($1)`
+
+const setParent = `UPDATE devices SET parent_id = $1, version = version + 1 WHERE id = $2`
+
+// parentOf returns the device d is part of, or nil.
+func parentOf(ctx context.Context, q querier, d *devices.Device) (*devices.Device, error) {
+	if d.ParentId == 0 {
+		return nil, nil
+	}
+
+	p, err := scanDevice(q.QueryRowContext(ctx, getDeviceById, d.ParentId))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// checkParentState refuses changes to d while the assembly it is part of
+// is in use.
+func checkParentState(ctx context.Context, q querier, d *devices.Device) error {
+	p, err := parentOf(ctx, q, d)
+	if err != nil {
+		return err
+	}
+	return d.CheckParentState(p)
+}
+
+// SetParent makes a device part of another one, or detaches it, and records
+// the change in the device history. Neither the device, its current parent
+// nor its new parent may be in use, and a device cannot become a part of
+// one of its own parts.
+func (s *service) SetParent(ctx context.Context, deviceId int64, a devices.Attach, version int64) (*devices.Device, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockComposition, compositionLockKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new parent is locked before the device, in the same order as a
+	// cascading checkout locks an assembly.
+	if a.ParentId != 0 {
+		parent, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, a.ParentId))
+		if errors.Is(err, devices.ErrNotExist) {
+			return nil, devices.ErrUnknownParent
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.IsDeviceInUse() {
+			return nil, devices.ErrAssemblyInUse
+		}
+	}
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.IsDeviceInUse() {
+		return nil, devices.ErrAssemblyInUse
+	}
+
+	err = checkParentState(ctx, tx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.ParentId != 0 {
+		ancestors, err := queryIds(ctx, tx, getAncestorIds, a.ParentId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = devices.CheckCycle(cur.Id, ancestors)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, setParent, nullId(a.ParentId), cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur.ParentId = a.ParentId
+	cur.Version++
+
+	err = recordHistory(ctx, tx, devices.HistoryAttached, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+// DeviceTree returns the device id with all of its parts, recursively.
+func (s *service) DeviceTree(ctx context.Context, id int64) (*devices.DeviceNode, error) {
+	rows, err := s.db.QueryContext(ctx, getDeviceTree, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dd, err := scanDevices(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return devices.BuildTree(id, dd)
+}
+
+// checkoutParts checks out every part of the device whose lease l was just
+// created, linking their leases to l so they are checked in with it.
+func checkoutParts(ctx context.Context, tx *sql.Tx, l *devices.Lease, c devices.Checkout) error {
+	ids, err := queryIds(ctx, tx, getPartIds, l.DeviceId)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		part, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, id))
+		if err != nil {
+			return err
+		}
+
+		_, err = checkout(ctx, tx, part, c, l.CheckedOutAt, l.Id)
+		if err != nil {
+			return fmt.Errorf("part %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+const getCascadedLeases = `SELECT device_id FROM device_leases
+WHERE cascaded_from = $1 AND returned_at IS NULL
+ORDER BY device_id`
+
+// checkinParts checks in the parts that were checked out along with the
+// lease l and records action in their history.
+func checkinParts(ctx context.Context, tx *sql.Tx, l *devices.Lease, action devices.HistoryAction) ([]devices.Lease, error) {
+	ids, err := queryIds(ctx, tx, getCascadedLeases, l.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var returned []devices.Lease
+	for _, id := range ids {
+		part, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, id))
+		if err != nil {
+			return nil, err
+		}
+
+		if part.Lease == nil || part.Lease.CascadedFrom != l.Id {
+			continue
+		}
+
+		pl, err := checkin(ctx, tx, part, action)
+		if err != nil {
+			return nil, err
+		}
+		returned = append(returned, *pl)
+	}
+
+	return returned, nil
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Config describes a Postgres database and its connection pool.
+type Config struct {
+	// DSN is a connection URL or keyword/value string. When set it is used
+	// as is, and the connection and TLS fields below are ignored.
+	DSN string
+
+	Host     string
+	Port     string
+	Database string
+	Username string
+	Password string
+
+	// SSLMode is a libpq sslmode such as "require" or "verify-full". It is
+	// "disable" when empty.
+	SSLMode string
+	// SSLRootCert, SSLCert and SSLKey are paths to the CA certificate and
+	// the client certificate and key.
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// SearchPath is the schema search path of every connection.
+	SearchPath string
+	// StatementTimeout aborts statements running longer. Zero leaves the
+	// server default.
+	StatementTimeout time.Duration
+
+	// MaxOpenConns and MaxIdleConns bound the pool. Zero leaves the
+	// database/sql defaults.
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections older than this. Zero keeps them
+	// forever.
+	ConnMaxLifetime time.Duration
+
+	// AutoMigrate applies pending migrations when the repository is opened.
+	AutoMigrate bool
+}
+
+// ConfigFromEnv reads the configuration from the DB_* variables:
+//
+//	DB_DSN                                              connection string, overrides the parts
+//	DB_HOST, DB_PORT, DB_DATABASE, DB_USERNAME, DB_PASSWORD
+//	DB_SSLMODE, DB_SSLROOTCERT, DB_SSLCERT, DB_SSLKEY
+//	DB_SCHEMA                                           search_path
+//	DB_STATEMENT_TIMEOUT, DB_CONN_MAX_LIFETIME          durations such as "30s"
+//	DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS
+//	DB_AUTO_MIGRATE                                     "true" to migrate on startup
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		DSN:         os.Getenv("DB_DSN"),
+		Host:        os.Getenv("DB_HOST"),
+		Port:        os.Getenv("DB_PORT"),
+		Database:    os.Getenv("DB_DATABASE"),
+		Username:    os.Getenv("DB_USERNAME"),
+		Password:    os.Getenv("DB_PASSWORD"),
+		SSLMode:     os.Getenv("DB_SSLMODE"),
+		SSLRootCert: os.Getenv("DB_SSLROOTCERT"),
+		SSLCert:     os.Getenv("DB_SSLCERT"),
+		SSLKey:      os.Getenv("DB_SSLKEY"),
+		SearchPath:  os.Getenv("DB_SCHEMA"),
+		AutoMigrate: os.Getenv("DB_AUTO_MIGRATE") == "true",
+	}
+
+	var err error
+	if cfg.StatementTimeout, err = envDuration("DB_STATEMENT_TIMEOUT"); err != nil {
+		return Config{}, err
+	}
+	if cfg.ConnMaxLifetime, err = envDuration("DB_CONN_MAX_LIFETIME"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS"); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS"); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func envDuration(key string) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: want a duration such as 30s", key, v)
+	}
+	return d, nil
+}
+
+func envInt(key string) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: want a non-negative number", key, v)
+	}
+	return n, nil
+}
+
+// connConfig parses the connection settings of c.
+func (c Config) connConfig() (*pgx.ConnConfig, error) {
+	dsn := c.DSN
+	if dsn == "" {
+		q := url.Values{}
+		q.Set("sslmode", c.SSLMode)
+		if c.SSLMode == "" {
+			q.Set("sslmode", "disable")
+		}
+		for k, v := range map[string]string{"sslrootcert": c.SSLRootCert, "sslcert": c.SSLCert, "sslkey": c.SSLKey} {
+			if v != "" {
+				q.Set(k, v)
+			}
+		}
+
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.Username, c.Password),
+			Host:     c.Host,
+			Path:     "/" + c.Database,
+			RawQuery: q.Encode(),
+		}
+		if c.Port != "" {
+			u.Host = net.JoinHostPort(c.Host, c.Port)
+		}
+		dsn = u.String()
+	}
+
+	cc, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing database config: %w", err)
+	}
+
+	if c.SearchPath != "" {
+		cc.RuntimeParams["search_path"] = c.SearchPath
+	}
+	if c.StatementTimeout > 0 {
+		cc.RuntimeParams["statement_timeout"] = strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	}
+
+	return cc, nil
+}
+
+// Open connects to the database described by cfg and checks that it is
+// reachable.
+func Open(ctx context.Context, cfg Config) (*sql.DB, error) {
+	cc, err := cfg.connConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	db := stdlib.OpenDB(*cc)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to database %s: %w", cc.Database, err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "db.lab")
+	t.Setenv("DB_PORT", "6432")
+	t.Setenv("DB_DATABASE", "devices")
+	t.Setenv("DB_USERNAME", "api")
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("DB_SCHEMA", "inventory")
+	t.Setenv("DB_SSLMODE", "verify-full")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "5s")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "30m")
+	t.Setenv("DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("DB_MAX_IDLE_CONNS", "5")
+	t.Setenv("DB_AUTO_MIGRATE", "true")
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Host:             "db.lab",
+		Port:             "6432",
+		Database:         "devices",
+		Username:         "api",
+		Password:         "s3cret",
+		SSLMode:          "verify-full",
+		SearchPath:       "inventory",
+		StatementTimeout: 5 * time.Second,
+		MaxOpenConns:     20,
+		MaxIdleConns:     5,
+		ConnMaxLifetime:  30 * time.Minute,
+		AutoMigrate:      true,
+	}, cfg)
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid DB_MAX_OPEN_CONNS "many": want a non-negative number`)
+
+	t.Setenv("DB_MAX_OPEN_CONNS", "")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "5")
+	_, err = ConfigFromEnv()
+	assert.EqualError(t, err, `invalid DB_STATEMENT_TIMEOUT "5": want a duration such as 30s`)
+}
+
+func TestConnConfig(t *testing.T) {
+	cc, err := Config{
+		Host:             "db.lab",
+		Port:             "6432",
+		Database:         "devices",
+		Username:         "api",
+		Password:         "p@ss/word",
+		SearchPath:       "inventory",
+		StatementTimeout: 1500 * time.Millisecond,
+	}.connConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "db.lab", cc.Host)
+	assert.Equal(t, uint16(6432), cc.Port)
+	assert.Equal(t, "devices", cc.Database)
+	assert.Equal(t, "api", cc.User)
+	assert.Equal(t, "p@ss/word", cc.Password)
+	assert.Nil(t, cc.TLSConfig, "sslmode defaults to disable")
+	assert.Equal(t, "inventory", cc.RuntimeParams["search_path"])
+	assert.Equal(t, "1500", cc.RuntimeParams["statement_timeout"])
+
+	cc, err = Config{Host: "db.lab", SSLMode: "require"}.connConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, cc.TLSConfig)
+
+	// A DSN replaces the connection fields.
+	cc, err = Config{DSN: "host=other dbname=assets user=ops sslmode=disable", Host: "db.lab", SearchPath: "inventory"}.connConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "other", cc.Host)
+	assert.Equal(t, "assets", cc.Database)
+	assert.Equal(t, "inventory", cc.RuntimeParams["search_path"])
+
+	_, err = Config{Host: "db.lab", SSLMode: "sometimes"}.connConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"strings"
+)
+
+const selectUsers = `SELECT id, name, email, created_at FROM users `
+
+const getUserById = selectUsers + `WHERE id = $1`
+
+const getAllUsers = selectUsers + `ORDER BY name, id`
+
+func scanUser(row rowScanner) (*devices.User, error) {
+	var (
+		u     devices.User
+		email sql.NullString
+	)
+
+	err := row.Scan(&u.Id, &u.Name, &email, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Email = email.String
+
+	return &u, nil
+}
+
+// getUser reads a user that a write refers to, so a missing one is reported
+// as ErrUnknownUser rather than ErrNotExist.
+func getUser(ctx context.Context, q querier, id int64) (*devices.User, error) {
+	u, err := scanUser(q.QueryRowContext(ctx, getUserById, id))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil, devices.ErrUnknownUser
+	}
+	return u, err
+}
+
+const createUser = `INSERT INTO users (name, email, created_at) VALUES ($1, $2, NOW()) RETURNING id`
+
+func (s *service) CreateUser(ctx context.Context, cu devices.CreateUser) (*devices.User, error) {
+	name := strings.TrimSpace(cu.Name)
+	if name == "" {
+		return nil, devices.ErrInvalidUser
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, createUser, name, nullString(strings.TrimSpace(cu.Email))).Scan(&id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, &devices.ErrDuplicateField{Field: "email", Value: cu.Email}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, id)
+}
+
+func (s *service) GetUser(ctx context.Context, id int64) (*devices.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, getUserById, id))
+}
+
+func (s *service) Users(ctx context.Context) ([]devices.User, error) {
+	rows, err := s.db.QueryContext(ctx, getAllUsers)
+	if err != nil {
+		return []devices.User{}, err
+	}
+	defer rows.Close()
+
+	uu := []devices.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return []devices.User{}, err
+		}
+		uu = append(uu, *u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.User{}, err
+	}
+
+	return uu, nil
+}
+
+const selectTeams = `SELECT id, name, created_at FROM teams `
+
+const getTeamById = selectTeams + `WHERE id = $1`
+
+const getAllTeams = selectTeams + `ORDER BY name`
+
+func scanTeam(row rowScanner) (*devices.Team, error) {
+	var t devices.Team
+
+	err := row.Scan(&t.Id, &t.Name, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// getTeam reads a team that a write refers to, so a missing one is reported
+// as ErrUnknownTeam rather than ErrNotExist.
+func getTeam(ctx context.Context, q querier, id int64) (*devices.Team, error) {
+	t, err := scanTeam(q.QueryRowContext(ctx, getTeamById, id))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil, devices.ErrUnknownTeam
+	}
+	return t, err
+}
+
+const createTeam = `INSERT INTO teams (name, created_at) VALUES ($1, NOW()) RETURNING id`
+
+func (s *service) CreateTeam(ctx context.Context, ct devices.CreateTeam) (*devices.Team, error) {
+	name := strings.TrimSpace(ct.Name)
+	if name == "" {
+		return nil, devices.ErrInvalidTeam
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, createTeam, name).Scan(&id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, &devices.ErrDuplicateField{Field: "name", Value: name}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTeam(ctx, id)
+}
+
+func (s *service) GetTeam(ctx context.Context, id int64) (*devices.Team, error) {
+	return scanTeam(s.db.QueryRowContext(ctx, getTeamById, id))
+}
+
+func (s *service) Teams(ctx context.Context) ([]devices.Team, error) {
+	rows, err := s.db.QueryContext(ctx, getAllTeams)
+	if err != nil {
+		return []devices.Team{}, err
+	}
+	defer rows.Close()
+
+	tt := []devices.Team{}
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return []devices.Team{}, err
+		}
+		tt = append(tt, *t)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Team{}, err
+	}
+
+	return tt, nil
+}
+
+const insertTeamMember = `INSERT INTO team_members (team_id, user_id) VALUES ($1, $2)
+ON CONFLICT (team_id, user_id) DO NOTHING`
+
+// AddTeamMember makes a user a member of a team. Adding a member twice does
+// nothing.
+func (s *service) AddTeamMember(ctx context.Context, teamId int64, m devices.Membership) error {
+	_, err := s.GetTeam(ctx, teamId)
+	if err != nil {
+		return err
+	}
+	_, err = getUser(ctx, s.db, m.UserId)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, insertTeamMember, teamId, m.UserId)
+	return err
+}
+
+const deleteTeamMember = `DELETE FROM team_members WHERE team_id = $1 AND user_id = $2`
+
+func (s *service) RemoveTeamMember(ctx context.Context, teamId, userId int64) error {
+	result, err := s.db.ExecContext(ctx, deleteTeamMember, teamId, userId)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
+
+const getTeamMembers = `SELECT u.id, u.name, u.email, u.created_at FROM users u
+JOIN team_members m ON m.user_id = u.id
+WHERE m.team_id = $1
+ORDER BY u.name, u.id`
+
+func (s *service) TeamMembers(ctx context.Context, teamId int64) ([]devices.User, error) {
+	_, err := s.GetTeam(ctx, teamId)
+	if err != nil {
+		return []devices.User{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, getTeamMembers, teamId)
+	if err != nil {
+		return []devices.User{}, err
+	}
+	defer rows.Close()
+
+	uu := []devices.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return []devices.User{}, err
+		}
+		uu = append(uu, *u)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.User{}, err
+	}
+
+	return uu, nil
+}
+
+const isTeamMember = `SELECT EXISTS (SELECT 1 FROM team_members WHERE team_id = $1 AND user_id = $2)`
+
+// checkTeamMember returns ErrNotTeamMember unless userId is a member of
+// teamId.
+func checkTeamMember(ctx context.Context, q querier, teamId, userId int64) error {
+	var member bool
+	err := q.QueryRowContext(ctx, isTeamMember, teamId, userId).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return devices.ErrNotTeamMember
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"devices_api/internal/devices"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes mapped to repository errors.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	exclusionViolation  = "23P01"
+)
+
+// pgErrorCode returns the SQLSTATE of err, or an empty string if err did not
+// come from the server.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// uniqueDeviceFields maps the unique constraints of the devices table to the
+// JSON name of the column.
+var uniqueDeviceFields = map[string]string{
+	"devices_serial_number_key": "serial_number",
+	"devices_asset_tag_key":     "asset_tag",
+}
+
+// deviceWriteError maps a unique violation on d to an
+// *devices.ErrDuplicateField naming the conflicting field.
+func deviceWriteError(err error, d *devices.Device) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+
+	switch uniqueDeviceFields[pgErr.ConstraintName] {
+	case "serial_number":
+		return &devices.ErrDuplicateField{Field: "serial_number", Value: d.SerialNumber}
+	case "asset_tag":
+		return &devices.ErrDuplicateField{Field: "asset_tag", Value: d.AssetTag}
+	}
+	return devices.ErrDuplicate
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func marshalAttributes(attrs map[string]any) ([]byte, error) {
+	if attrs == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attrs)
+}
+
+func unmarshalAttributes(b []byte) (map[string]any, error) {
+	var attrs map[string]any
+	err := json.Unmarshal(b, &attrs)
+	if err != nil {
+		return nil, err
+	}
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	return attrs, nil
+}
+
+// whereBuilder collects SQL conditions and their arguments. Conditions are
+// fixed fragments with %s in place of each argument, so user input only
+// ever reaches the database as a bind parameter.
+type whereBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (b *whereBuilder) add(cond string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, a := range args {
+		b.args = append(b.args, a)
+		placeholders[i] = "$" + strconv.Itoa(len(b.args))
+	}
+	b.conds = append(b.conds, fmt.Sprintf(cond, placeholders...))
+}
+
+func (b *whereBuilder) String() string {
+	return strings.Join(b.conds, " AND ")
+}
+
+// attributeComparisons holds the numeric comparison for each ordering
+// operator. Non-numeric attributes never match.
+var attributeComparisons = map[devices.AttributeOp]string{
+	devices.AttrGt: `CASE WHEN jsonb_typeof(d.attributes -> %[1]s::text) = 'number' THEN (d.attributes ->> %[1]s::text)::numeric > %[2]s::numeric ELSE false END`,
+	devices.AttrGe: `CASE WHEN jsonb_typeof(d.attributes -> %[1]s::text) = 'number' THEN (d.attributes ->> %[1]s::text)::numeric >= %[2]s::numeric ELSE false END`,
+	devices.AttrLt: `CASE WHEN jsonb_typeof(d.attributes -> %[1]s::text) = 'number' THEN (d.attributes ->> %[1]s::text)::numeric < %[2]s::numeric ELSE false END`,
+	devices.AttrLe: `CASE WHEN jsonb_typeof(d.attributes -> %[1]s::text) = 'number' THEN (d.attributes ->> %[1]s::text)::numeric <= %[2]s::numeric ELSE false END`,
+}
+
+func (b *whereBuilder) addAttribute(af devices.AttributeFilter) error {
+	switch af.Op {
+	case devices.AttrEq, devices.AttrNe:
+		// Containment is served by the GIN index on attributes.
+		doc, err := json.Marshal(map[string]any{af.Key: af.Value})
+		if err != nil {
+			return err
+		}
+		if af.Op == devices.AttrEq {
+			b.add(`d.attributes @> %s::jsonb`, string(doc))
+		} else {
+			b.add(`NOT d.attributes @> %s::jsonb`, string(doc))
+		}
+		return nil
+	}
+
+	cmp, ok := attributeComparisons[af.Op]
+	if !ok {
+		return fmt.Errorf("%w: unknown operator %q", devices.ErrInvalidFilter, af.Op)
+	}
+	b.add(cmp, af.Key, strconv.FormatFloat(af.Value.(float64), 'f', -1, 64))
+	return nil
+}
+
+// labelRequirements holds the condition for each selector operator. As in
+// Kubernetes, != and notin also match devices without the label.
+var labelRequirements = map[devices.SelectorOp]string{
+	devices.SelectEquals:       `EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s AND dl.value = %s)`,
+	devices.SelectNotEquals:    `NOT EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s AND dl.value = %s)`,
+	devices.SelectIn:           `EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s AND dl.value = ANY(%s::text[]))`,
+	devices.SelectNotIn:        `NOT EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s AND dl.value = ANY(%s::text[]))`,
+	devices.SelectExists:       `EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s)`,
+	devices.SelectDoesNotExist: `NOT EXISTS (SELECT 1 FROM device_labels dl WHERE dl.device_id = d.id AND dl.key = %s)`,
+}
+
+func (b *whereBuilder) addRequirement(r devices.Requirement) error {
+	cond, ok := labelRequirements[r.Op]
+	if !ok {
+		return fmt.Errorf("%w: unknown selector operator %q", devices.ErrInvalidFilter, r.Op)
+	}
+
+	switch r.Op {
+	case devices.SelectEquals, devices.SelectNotEquals:
+		b.add(cond, r.Key, r.Values[0])
+	case devices.SelectIn, devices.SelectNotIn:
+		b.add(cond, r.Key, r.Values)
+	default:
+		b.add(cond, r.Key)
+	}
+	return nil
+}
+
+// buildFilter translates f into a WHERE clause for selectDevices.
+func buildFilter(f devices.DeviceFilter) (*whereBuilder, error) {
+	b := &whereBuilder{}
+	b.add(`d.deleted_at IS NULL`)
+
+	err := b.addFilter(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// addFilter adds a condition for every part of f.
+func (b *whereBuilder) addFilter(f devices.DeviceFilter) error {
+	for _, af := range f.Attributes {
+		err := b.addAttribute(af)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range f.Labels {
+		err := b.addRequirement(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	if f.WarrantyExpiresBefore != nil {
+		b.add(`d.warranty_expires_on < %s`, *f.WarrantyExpiresBefore)
+	}
+
+	if f.EndOfLifeBefore != nil {
+		b.add(`d.end_of_life_on < %s`, *f.EndOfLifeBefore)
+	}
+
+	return nil
+}
+
+// Find lists the devices matching f, ordered by id.
+func (s *service) Find(ctx context.Context, f devices.DeviceFilter) ([]devices.Device, error) {
+	b, err := buildFilter(f)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectDevices+`WHERE `+b.String()+` ORDER BY d.id`, b.args...)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
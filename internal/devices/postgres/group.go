@@ -0,0 +1,423 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// selectGroups lists the static members of each group that are not in the
+// trash.
+const selectGroups = `SELECT g.id, g.name, g.description, g.filter, g.created_at,
+  COALESCE((
+    SELECT json_agg(m.device_id ORDER BY m.device_id)
+    FROM device_group_members m JOIN devices d ON d.id = m.device_id
+    WHERE m.group_id = g.id AND d.deleted_at IS NULL
+  ), '[]')
+FROM device_groups g
+`
+
+const getGroupById = selectGroups + `WHERE g.id = $1`
+
+const getAllGroups = selectGroups + `ORDER BY g.name`
+
+func scanGroup(row rowScanner) (*devices.Group, error) {
+	var (
+		g         devices.Group
+		deviceIds []byte
+	)
+
+	err := row.Scan(&g.Id, &g.Name, &g.Description, &g.Filter, &g.CreatedAt, &deviceIds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(deviceIds, &g.DeviceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+const createGroup = `INSERT INTO device_groups (name, description, filter, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id`
+
+func (s *service) CreateGroup(ctx context.Context, cg devices.CreateGroup) (*devices.Group, error) {
+	_, err := cg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, createGroup, strings.TrimSpace(cg.Name), cg.Description, cg.Filter).Scan(&id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, id)
+}
+
+func (s *service) GetGroup(ctx context.Context, id int64) (*devices.Group, error) {
+	return scanGroup(s.db.QueryRowContext(ctx, getGroupById, id))
+}
+
+func (s *service) Groups(ctx context.Context) ([]devices.Group, error) {
+	rows, err := s.db.QueryContext(ctx, getAllGroups)
+	if err != nil {
+		return []devices.Group{}, err
+	}
+	defer rows.Close()
+
+	gg := []devices.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return []devices.Group{}, err
+		}
+		gg = append(gg, *g)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Group{}, err
+	}
+
+	return gg, nil
+}
+
+const updateGroup = `UPDATE device_groups SET name = $1, description = $2, filter = $3 WHERE id = $4`
+
+// UpdateGroup changes the name, description and filter of a group. The
+// static members are kept.
+func (s *service) UpdateGroup(ctx context.Context, id int64, cg devices.CreateGroup) (*devices.Group, error) {
+	_, err := cg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.ExecContext(ctx, updateGroup, strings.TrimSpace(cg.Name), cg.Description, cg.Filter, id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, devices.ErrNotExist
+	}
+
+	return s.GetGroup(ctx, id)
+}
+
+const deleteGroup = `DELETE FROM device_groups WHERE id = $1`
+
+// DeleteGroup removes a group. Its members are not affected.
+func (s *service) DeleteGroup(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, deleteGroup, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
+
+const getMissingDevices = `SELECT m.device_id FROM unnest($1::integer[]) AS m(device_id)
+WHERE NOT EXISTS (SELECT 1 FROM devices d WHERE d.id = m.device_id AND d.deleted_at IS NULL)
+ORDER BY m.device_id`
+
+const addGroupMembers = `INSERT INTO device_group_members (group_id, device_id)
+SELECT $1, unnest($2::integer[])
+ON CONFLICT DO NOTHING`
+
+// AddGroupMembers adds devices to the static members of a group. Devices
+// that already are members are skipped, unknown or trashed ones rejected.
+func (s *service) AddGroupMembers(ctx context.Context, groupId int64, deviceIds []int64) (*devices.Group, error) {
+	if len(deviceIds) == 0 {
+		return nil, fmt.Errorf("%w: no devices to add", devices.ErrInvalidGroup)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = scanGroup(tx.QueryRowContext(ctx, getGroupById, groupId))
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := queryIds(ctx, tx, getMissingDevices, deviceIds)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: unknown devices %v", devices.ErrInvalidGroup, missing)
+	}
+
+	_, err = tx.ExecContext(ctx, addGroupMembers, groupId, deviceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := scanGroup(tx.QueryRowContext(ctx, getGroupById, groupId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+const removeGroupMember = `DELETE FROM device_group_members WHERE group_id = $1 AND device_id = $2`
+
+// RemoveGroupMember removes a device from the static members of a group. A
+// device that only matches the filter of the group stays a member.
+func (s *service) RemoveGroupMember(ctx context.Context, groupId int64, deviceId int64) (*devices.Group, error) {
+	result, err := s.db.ExecContext(ctx, removeGroupMember, groupId, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, devices.ErrNotExist
+	}
+
+	return s.GetGroup(ctx, groupId)
+}
+
+// groupMembers builds the condition for the members of g: its static
+// members and, when it has a filter, every device matching it.
+func groupMembers(g *devices.Group) (*whereBuilder, error) {
+	b := &whereBuilder{}
+	b.add(`d.id IN (SELECT device_id FROM device_group_members WHERE group_id = %s)`, g.Id)
+
+	if g.Filter != "" {
+		f, err := devices.ParseDeviceFilter(g.Filter)
+		if err != nil {
+			return nil, err
+		}
+
+		static := b.conds[0]
+		b.conds = nil
+		err = b.addFilter(f)
+		if err != nil {
+			return nil, err
+		}
+		b.conds = []string{fmt.Sprintf(`(%s OR (%s))`, static, b.String())}
+	}
+
+	b.add(`d.deleted_at IS NULL`)
+	return b, nil
+}
+
+func (s *service) GroupMembers(ctx context.Context, groupId int64) ([]devices.Device, error) {
+	g, err := s.GetGroup(ctx, groupId)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+
+	b, err := groupMembers(g)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectDevices+`WHERE `+b.String()+` ORDER BY d.id`, b.args...)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// Each member is changed under a savepoint, so a member that fails is
+// rolled back on its own while the others are kept.
+const (
+	savepointMember  = `SAVEPOINT group_member`
+	rollbackMember   = `ROLLBACK TO SAVEPOINT group_member`
+	releaseMember    = `RELEASE SAVEPOINT group_member`
+	updateGroupState = `UPDATE devices SET d_state = $1, version = version + 1 WHERE id = $2`
+)
+
+// ApplyToGroup applies the action to every member of the group in one
+// transaction. The members are locked one at a time in id order, so two
+// group actions on overlapping groups cannot deadlock.
+func (s *service) ApplyToGroup(ctx context.Context, groupId int64, a devices.GroupAction) (*devices.GroupReport, error) {
+	err := a.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	g, err := scanGroup(tx.QueryRowContext(ctx, getGroupById, groupId))
+	if err != nil {
+		return nil, err
+	}
+
+	if a.TransferToTeamId != 0 {
+		_, err = getTeam(ctx, tx, a.TransferToTeamId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := groupMembers(g)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := queryIds(ctx, tx, `SELECT d.id FROM devices d WHERE `+b.String()+` ORDER BY d.id`, b.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &devices.GroupReport{GroupId: g.Id, Results: []devices.GroupResult{}}
+	now := time.Now()
+	for _, id := range ids {
+		_, err = tx.ExecContext(ctx, savepointMember)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := applyGroupAction(ctx, tx, id, a, now)
+		if err != nil {
+			_, rbErr := tx.ExecContext(ctx, rollbackMember)
+			if rbErr != nil {
+				return nil, rbErr
+			}
+			_, rbErr = tx.ExecContext(ctx, releaseMember)
+			if rbErr != nil {
+				return nil, rbErr
+			}
+			report.Add(id, nil, err)
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, releaseMember)
+		if err != nil {
+			return nil, err
+		}
+		report.Add(id, d, nil)
+	}
+
+	if a.Atomic && report.Failed > 0 {
+		report.RollBack()
+		return report, devices.ErrGroupActionFailed
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// applyGroupAction changes a single member and records the change in its
+// history. Devices the target team already owns get no transfer.
+func applyGroupAction(ctx context.Context, tx *sql.Tx, deviceId int64, a devices.GroupAction, now time.Time) (*devices.Device, error) {
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	if a.ChangesDevice() {
+		before := *cur
+
+		if a.State != nil && *a.State != cur.State {
+			err = checkParentState(ctx, tx, cur)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = a.Apply(cur)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range a.RemoveLabels {
+			_, err = tx.ExecContext(ctx, deleteLabel, cur.Id, k)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = setLabels(ctx, tx, cur.Id, a.SetLabels)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, updateGroupState, cur.State, cur.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		cur, err = scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+		if err != nil {
+			return nil, err
+		}
+
+		err = recordHistory(ctx, tx, devices.HistoryGroupAction, &before, cur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.TransferToTeamId != 0 && a.TransferToTeamId != cur.OwnerId {
+		t, err := cur.RequestTransfer(devices.CreateTransfer{ToTeamId: a.TransferToTeamId}, devices.ActorFromContext(ctx), now)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, insertTransfer,
+			t.DeviceId, nullId(t.FromTeamId), t.ToTeamId, t.Status, t.RequestedBy, t.RequestedAt,
+		)
+		if pgErrorCode(err) == uniqueViolation {
+			return nil, devices.ErrTransferPending
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cur, nil
+}
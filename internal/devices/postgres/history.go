@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"encoding/json"
+	"time"
+)
+
+const insertHistory = `INSERT INTO device_history (
+  device_id,
+  action,
+  actor,
+  before,
+  after,
+  changed_at
+) VALUES (
+  $1, $2, $3, $4, $5, NOW()
+)`
+
+// recordHistory writes a before/after snapshot of a device change. It runs on
+// the caller's transaction so the history can never disagree with the row.
+func recordHistory(ctx context.Context, tx *sql.Tx, action devices.HistoryAction, before, after *devices.Device) error {
+	var deviceId int64
+	if after != nil {
+		deviceId = after.Id
+	} else {
+		deviceId = before.Id
+	}
+
+	b, err := marshalSnapshot(before)
+	if err != nil {
+		return err
+	}
+
+	a, err := marshalSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertHistory, deviceId, string(action), devices.ActorFromContext(ctx), b, a)
+	return err
+}
+
+func marshalSnapshot(d *devices.Device) ([]byte, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func unmarshalSnapshot(b []byte) (*devices.Device, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	var d devices.Device
+	err := json.Unmarshal(b, &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+const getDeviceHistory = `SELECT id, device_id, action, actor, before, after, changed_at FROM device_history
+WHERE device_id = $1
+  AND ($2::timestamptz IS NULL OR changed_at >= $2)
+  AND ($3::timestamptz IS NULL OR changed_at < $3)
+ORDER BY changed_at, id`
+
+func (s *service) History(ctx context.Context, deviceId int64, f devices.HistoryFilter) ([]devices.HistoryEntry, error) {
+	rows, err := s.db.QueryContext(ctx, getDeviceHistory, deviceId, nullTime(f.From), nullTime(f.To))
+	if err != nil {
+		return []devices.HistoryEntry{}, err
+	}
+	defer rows.Close()
+
+	hh := []devices.HistoryEntry{}
+	for rows.Next() {
+		var (
+			h             devices.HistoryEntry
+			action        string
+			before, after []byte
+		)
+
+		err = rows.Scan(&h.Id, &h.DeviceId, &action, &h.Actor, &before, &after, &h.ChangedAt)
+		if err != nil {
+			return []devices.HistoryEntry{}, err
+		}
+		h.Action = devices.HistoryAction(action)
+
+		h.Before, err = unmarshalSnapshot(before)
+		if err != nil {
+			return []devices.HistoryEntry{}, err
+		}
+
+		h.After, err = unmarshalSnapshot(after)
+		if err != nil {
+			return []devices.HistoryEntry{}, err
+		}
+
+		hh = append(hh, h)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.HistoryEntry{}, err
+	}
+
+	return hh, nil
+}
+
+// nullTime maps the zero time to NULL so open-ended ranges can be expressed
+// with a single query.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"encoding/json"
+)
+
+func unmarshalLabels(b []byte) (map[string]string, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	var labels map[string]string
+	err := json.Unmarshal(b, &labels)
+	if err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+const upsertLabel = `INSERT INTO device_labels (device_id, key, value) VALUES ($1, $2, $3)
+ON CONFLICT (device_id, key) DO UPDATE SET value = EXCLUDED.value`
+
+const deleteLabel = `DELETE FROM device_labels WHERE device_id = $1 AND key = $2`
+
+const deleteAllLabels = `DELETE FROM device_labels WHERE device_id = $1`
+
+func setLabels(ctx context.Context, tx *sql.Tx, deviceId int64, labels map[string]string) error {
+	for k, v := range labels {
+		_, err := tx.ExecContext(ctx, upsertLabel, deviceId, k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const touchDevice = `UPDATE devices SET version = version + 1 WHERE id = $1`
+
+// UpdateLabels changes the labels of a device in place and records the change
+// in the device history. Removing a label the device does not have is not an
+// error.
+func (s *service) UpdateLabels(ctx context.Context, deviceId int64, set map[string]string, remove []string) (map[string]string, error) {
+	err := devices.ValidateLabels(set)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	for _, k := range remove {
+		_, err = tx.ExecContext(ctx, deleteLabel, deviceId, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = setLabels(ctx, tx, deviceId, set)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, touchDevice, deviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err = scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordHistory(ctx, tx, devices.HistoryLabels, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return cur.Labels, nil
+}
@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// nullLease holds the nullable lease columns of the devices projection.
+type nullLease struct {
+	id               sql.NullInt64
+	holder           sql.NullString
+	checkedOutAt     sql.NullTime
+	expectedReturnAt sql.NullTime
+	cascadedFrom     sql.NullInt64
+}
+
+func (l nullLease) lease(deviceId int64) *devices.Lease {
+	if !l.id.Valid {
+		return nil
+	}
+	return &devices.Lease{
+		Id:               l.id.Int64,
+		DeviceId:         deviceId,
+		Holder:           l.holder.String,
+		CheckedOutAt:     l.checkedOutAt.Time,
+		ExpectedReturnAt: l.expectedReturnAt.Time,
+		CascadedFrom:     l.cascadedFrom.Int64,
+	}
+}
+
+const insertLease = `INSERT INTO device_leases (
+  device_id,
+  holder,
+  checked_out_at,
+  expected_return_at,
+  cascaded_from
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING id`
+
+const returnLease = `UPDATE device_leases SET returned_at = $1 WHERE id = $2`
+
+// Checkout hands the device to c.Holder. It is refused if the lease window
+// overlaps a reservation held by someone else or a maintenance window, or
+// if the device is part of an in-use assembly. A cascading checkout also
+// checks out every part of the device, and fails as a whole if any of them
+// cannot be checked out.
+func (s *service) Checkout(ctx context.Context, deviceId int64, c devices.Checkout) (*devices.Lease, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkParentState(ctx, tx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := checkout(ctx, tx, cur, c, time.Now(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Cascade {
+		err = checkoutParts(ctx, tx, l, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// checkout hands cur, a device row locked by tx, to c.Holder and records it
+// in the device history. cascadedFrom is the lease of the parent for parts
+// checked out along with it.
+func checkout(ctx context.Context, tx *sql.Tx, cur *devices.Device, c devices.Checkout, now time.Time, cascadedFrom int64) (*devices.Lease, error) {
+	before := *cur
+
+	rr, err := queryReservations(ctx, tx, devices.ReservationFilter{DeviceId: cur.Id, From: now, To: c.ExpectedReturnAt})
+	if err != nil {
+		return nil, err
+	}
+
+	err = devices.CheckReserved(rr, c.Holder, now, c.ExpectedReturnAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMaintenance(ctx, tx, cur.Id, now, c.ExpectedReturnAt)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := cur.CheckOut(c, now)
+	if err != nil {
+		return nil, err
+	}
+	l.CascadedFrom = cascadedFrom
+
+	_, err = tx.ExecContext(ctx, updateDeviceState, cur.State, cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur.Version++
+
+	err = tx.QueryRowContext(ctx, insertLease, l.DeviceId, l.Holder, l.CheckedOutAt, l.ExpectedReturnAt, nullId(cascadedFrom)).Scan(&l.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordHistory(ctx, tx, devices.HistoryCheckedOut, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Checkin ends the active lease of the device, together with the leases of
+// the parts checked out along with it. A part cannot be checked in on its
+// own while its parent is in use.
+func (s *service) Checkin(ctx context.Context, deviceId int64) (*devices.Lease, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkParentState(ctx, tx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := checkin(ctx, tx, cur, devices.HistoryCheckedIn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkinParts(ctx, tx, l, devices.HistoryCheckedIn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// checkin ends the active lease of cur, a device row locked by tx, and
+// records action in the device history.
+func checkin(ctx context.Context, tx *sql.Tx, cur *devices.Device, action devices.HistoryAction) (*devices.Lease, error) {
+	before := *cur
+
+	l, err := cur.CheckIn(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, updateDeviceState, cur.State, cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur.Version++
+
+	_, err = tx.ExecContext(ctx, returnLease, l.ReturnedAt, l.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = recordHistory(ctx, tx, action, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// getExpiredLeases selects the expired leases that were not checked out
+// along with another device; those are checked in with their parent.
+const getExpiredLeases = `SELECT id, device_id FROM device_leases
+WHERE returned_at IS NULL AND cascaded_from IS NULL AND expected_return_at < $1
+ORDER BY device_id`
+
+// lockLeaseDevices locks the device holding lease $2 together with the parts
+// checked out along with it, in id order.
+const lockLeaseDevices = `SELECT id FROM devices
+WHERE id = $1 OR id IN (
+	SELECT device_id FROM device_leases WHERE cascaded_from = $2 AND returned_at IS NULL
+)
+ORDER BY id FOR UPDATE`
+
+// ExpireLeases checks in every device whose lease expired before now,
+// together with the parts checked out along with it. Each lease is expired
+// in its own transaction, so a lease that fails is reported and retried on
+// the next run without holding back the others. The devices of a lease are
+// locked in id order and re-read, so a lease returned or renewed since the
+// scan is left alone.
+func (s *service) ExpireLeases(ctx context.Context, now time.Time) ([]devices.Lease, error) {
+	rows, err := s.db.QueryContext(ctx, getExpiredLeases, now)
+	if err != nil {
+		return nil, err
+	}
+
+	type due struct{ id, deviceId int64 }
+	var dd []due
+	for rows.Next() {
+		var d due
+		err = rows.Scan(&d.id, &d.deviceId)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dd = append(dd, d)
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []devices.Lease{}
+	var errs []error
+	for _, d := range dd {
+		ll, err := s.expireLease(ctx, d.id, d.deviceId, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lease %d of device %d: %w", d.id, d.deviceId, err))
+			continue
+		}
+		expired = append(expired, ll...)
+	}
+
+	return expired, errors.Join(errs...)
+}
+
+// expireLease checks in the device holding lease leaseId and its parts if
+// the lease is still out and expired at now.
+func (s *service) expireLease(ctx context.Context, leaseId, deviceId int64, now time.Time) ([]devices.Lease, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = queryIds(ctx, tx, lockLeaseDevices, deviceId, leaseId)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if errors.Is(err, devices.ErrNotExist) {
+		// The device is in the trash.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.Lease == nil || cur.Lease.Id != leaseId || !cur.Lease.Expired(now) {
+		return nil, nil
+	}
+
+	l, err := checkin(ctx, tx, cur, devices.HistoryLeaseExpired)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := checkinParts(ctx, tx, l, devices.HistoryLeaseExpired)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]devices.Lease{*l}, parts...), nil
+}
@@ -0,0 +1,273 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"strings"
+)
+
+const selectLocations = `SELECT id, parent_id, kind, name, created_at FROM locations `
+
+const getLocationById = selectLocations + `WHERE id = $1`
+
+const getAllLocations = selectLocations + `ORDER BY parent_id NULLS FIRST, name`
+
+func scanLocation(row rowScanner) (*devices.Location, error) {
+	var (
+		l        devices.Location
+		parentId sql.NullInt64
+	)
+
+	err := row.Scan(&l.Id, &parentId, &l.Kind, &l.Name, &l.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.ParentId = parentId.Int64
+
+	return &l, nil
+}
+
+// getLocation reads a location that a write refers to, so a missing one is
+// reported as ErrUnknownLocation rather than ErrNotExist.
+func getLocation(ctx context.Context, q querier, id int64) (*devices.Location, error) {
+	l, err := scanLocation(q.QueryRowContext(ctx, getLocationById, id))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil, devices.ErrUnknownLocation
+	}
+	return l, err
+}
+
+// parentLocation returns the parent a location is placed under, or nil for
+// a root location.
+func parentLocation(ctx context.Context, q querier, parentId int64) (*devices.Location, error) {
+	if parentId == 0 {
+		return nil, nil
+	}
+	return getLocation(ctx, q, parentId)
+}
+
+const createLocation = `INSERT INTO locations (parent_id, kind, name, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id`
+
+func (s *service) CreateLocation(ctx context.Context, cl devices.CreateLocation) (*devices.Location, error) {
+	name := strings.TrimSpace(cl.Name)
+	if name == "" {
+		return nil, devices.ErrInvalidLocation
+	}
+
+	parent, err := parentLocation(ctx, s.db, cl.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cl.Kind.CheckParent(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, createLocation, nullId(cl.ParentId), cl.Kind, name).Scan(&id)
+	switch pgErrorCode(err) {
+	case uniqueViolation:
+		return nil, devices.ErrDuplicate
+	case foreignKeyViolation:
+		return nil, devices.ErrUnknownLocation
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetLocation(ctx, id)
+}
+
+func (s *service) GetLocation(ctx context.Context, id int64) (*devices.Location, error) {
+	return scanLocation(s.db.QueryRowContext(ctx, getLocationById, id))
+}
+
+func (s *service) Locations(ctx context.Context) ([]devices.Location, error) {
+	rows, err := s.db.QueryContext(ctx, getAllLocations)
+	if err != nil {
+		return []devices.Location{}, err
+	}
+	defer rows.Close()
+
+	ll := []devices.Location{}
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return []devices.Location{}, err
+		}
+		ll = append(ll, *l)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Location{}, err
+	}
+
+	return ll, nil
+}
+
+// locationSubtree selects the location $1 and, when $2 is true, every
+// location below it.
+const locationSubtree = `WITH RECURSIVE subtree AS (
+  SELECT id FROM locations WHERE id = $1
+  UNION ALL
+  SELECT l.id FROM locations l JOIN subtree s ON l.parent_id = s.id WHERE $2::boolean
+)
+`
+
+const isInSubtree = locationSubtree + `SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $3)`
+
+const getLocationForUpdate = selectLocations + `WHERE id = $1 FOR UPDATE`
+
+const updateLocation = `UPDATE locations SET parent_id = $1, name = $2 WHERE id = $3`
+
+// UpdateLocation renames a location or moves it, with everything below it,
+// under another parent. A location cannot be moved below itself.
+func (s *service) UpdateLocation(ctx context.Context, id int64, ul devices.UpdateLocation) (*devices.Location, error) {
+	name := strings.TrimSpace(ul.Name)
+	if name == "" {
+		return nil, devices.ErrInvalidLocation
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanLocation(tx.QueryRowContext(ctx, getLocationForUpdate, id))
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := parentLocation(ctx, tx, ul.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.Kind.CheckParent(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	if ul.ParentId != 0 {
+		var cycle bool
+		err = tx.QueryRowContext(ctx, isInSubtree, id, true, ul.ParentId).Scan(&cycle)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, devices.ErrInvalidLocation
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, updateLocation, nullId(ul.ParentId), name, id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := scanLocation(tx.QueryRowContext(ctx, getLocationById, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+const deleteLocation = `DELETE FROM locations WHERE id = $1`
+
+// DeleteLocation removes an empty location. Locations holding devices,
+// including devices in the trash, or other locations cannot be deleted.
+func (s *service) DeleteLocation(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, deleteLocation, id)
+	if pgErrorCode(err) == foreignKeyViolation {
+		return devices.ErrLocationInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
+
+const moveDevice = `UPDATE devices SET location_id = $1, version = version + 1 WHERE id = $2`
+
+// MoveDevice moves a device to another location and records the move in the
+// device history.
+func (s *service) MoveDevice(ctx context.Context, deviceId int64, m devices.Move, version int64) (*devices.Device, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getLocation(ctx, tx, m.LocationId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, moveDevice, m.LocationId, cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur.LocationId = m.LocationId
+	cur.Version++
+
+	err = recordHistory(ctx, tx, devices.HistoryMoved, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+const getDevicesByLocation = locationSubtree + selectDevices + `WHERE d.deleted_at IS NULL AND d.location_id IN (SELECT id FROM subtree)
+ORDER BY d.id`
+
+func (s *service) GetByLocation(ctx context.Context, locationId int64, includeChildren bool) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByLocation, locationId, includeChildren)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
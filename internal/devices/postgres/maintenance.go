@@ -0,0 +1,302 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const selectMaintenance = `SELECT id, device_id, starts_at, ends_at, reason, technician, previous_state, started_at, ended_at, created_at
+FROM maintenance_windows
+`
+
+func scanMaintenance(row rowScanner) (*devices.MaintenanceWindow, error) {
+	var (
+		w    devices.MaintenanceWindow
+		prev sql.NullInt64
+	)
+
+	err := row.Scan(&w.Id, &w.DeviceId, &w.StartsAt, &w.EndsAt, &w.Reason, &w.Technician, &prev, &w.StartedAt, &w.EndedAt, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if prev.Valid {
+		ds := devices.DeviceState(prev.Int64)
+		w.PreviousState = &ds
+	}
+
+	return &w, nil
+}
+
+const insertMaintenance = `INSERT INTO maintenance_windows (
+  device_id,
+  starts_at,
+  ends_at,
+  reason,
+  technician,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, $5, NOW()
+) RETURNING id`
+
+const getMaintenanceById = selectMaintenance + `WHERE id = $1`
+
+// ScheduleMaintenance books a maintenance window for the device. Overlapping
+// windows are rejected by the exclusion constraint on maintenance_windows.
+func (s *service) ScheduleMaintenance(ctx context.Context, deviceId int64, cm devices.CreateMaintenance) (*devices.MaintenanceWindow, error) {
+	err := cm.Validate(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the device so a concurrent checkout sees the new window.
+	_, err = scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, insertMaintenance, deviceId, cm.StartsAt, cm.EndsAt, cm.Reason, cm.Technician).Scan(&id)
+	if pgErrorCode(err) == exclusionViolation {
+		return nil, devices.ErrMaintenanceConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := scanMaintenance(tx.QueryRowContext(ctx, getMaintenanceById, id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+const getMaintenanceWindows = selectMaintenance + `WHERE ($1::integer = 0 OR device_id = $1)
+  AND during && tstzrange($2, $3, '[)')
+ORDER BY starts_at, id`
+
+// MaintenanceWindows lists the windows overlapping the filter window. Zero
+// times are passed as NULL, which tstzrange treats as unbounded.
+func (s *service) MaintenanceWindows(ctx context.Context, f devices.MaintenanceFilter) ([]devices.MaintenanceWindow, error) {
+	return queryMaintenance(ctx, s.db, f)
+}
+
+func queryMaintenance(ctx context.Context, q querier, f devices.MaintenanceFilter) ([]devices.MaintenanceWindow, error) {
+	rows, err := q.QueryContext(ctx, getMaintenanceWindows, f.DeviceId, nullTime(f.From), nullTime(f.To))
+	if err != nil {
+		return []devices.MaintenanceWindow{}, err
+	}
+	defer rows.Close()
+
+	ww := []devices.MaintenanceWindow{}
+	for rows.Next() {
+		w, err := scanMaintenance(rows)
+		if err != nil {
+			return []devices.MaintenanceWindow{}, err
+		}
+		ww = append(ww, *w)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.MaintenanceWindow{}, err
+	}
+
+	return ww, nil
+}
+
+// checkMaintenance returns ErrDeviceInMaintenance if a maintenance window of
+// the device overlaps [start, end).
+func checkMaintenance(ctx context.Context, q querier, deviceId int64, start, end time.Time) error {
+	ww, err := queryMaintenance(ctx, q, devices.MaintenanceFilter{DeviceId: deviceId, From: start, To: end})
+	if err != nil {
+		return err
+	}
+
+	return devices.CheckMaintenance(ww, start, end)
+}
+
+const getMaintenanceForUpdate = selectMaintenance + `WHERE id = $1 AND device_id = $2 FOR UPDATE`
+
+const deleteMaintenance = `DELETE FROM maintenance_windows WHERE id = $1`
+
+func (s *service) CancelMaintenance(ctx context.Context, deviceId int64, windowId int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	w, err := scanMaintenance(tx.QueryRowContext(ctx, getMaintenanceForUpdate, windowId, deviceId))
+	if err != nil {
+		return err
+	}
+
+	if w.StartedAt != nil {
+		return devices.ErrMaintenanceStarted
+	}
+
+	_, err = tx.ExecContext(ctx, deleteMaintenance, w.Id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getDueMaintenance selects the windows to start or end at $1. Windows that
+// elapsed without ever starting are left alone.
+const getDueMaintenance = `SELECT id, device_id FROM maintenance_windows
+WHERE (started_at IS NULL AND starts_at <= $1 AND ends_at > $1)
+   OR (started_at IS NOT NULL AND ended_at IS NULL AND ends_at <= $1)
+ORDER BY device_id, id`
+
+const startMaintenance = `UPDATE maintenance_windows SET previous_state = $1, started_at = $2 WHERE id = $3`
+
+const endMaintenance = `UPDATE maintenance_windows SET ended_at = $1 WHERE id = $2`
+
+// ApplyMaintenance starts the windows due at now and ends the elapsed ones.
+// Each window is applied in its own transaction, so a window that fails is
+// reported and retried on the next run without holding back the others.
+// Like ExpireLeases, the windows are taken in device id order and each
+// device is locked before its window and re-checked, so windows changed
+// since the scan are left alone. Devices in use stay in use and enter
+// maintenance on a later run, once they are checked in.
+func (s *service) ApplyMaintenance(ctx context.Context, now time.Time) ([]devices.MaintenanceWindow, error) {
+	rows, err := s.db.QueryContext(ctx, getDueMaintenance, now)
+	if err != nil {
+		return nil, err
+	}
+
+	type due struct{ id, deviceId int64 }
+	var dd []due
+	for rows.Next() {
+		var d due
+		err = rows.Scan(&d.id, &d.deviceId)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		dd = append(dd, d)
+	}
+	rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []devices.MaintenanceWindow{}
+	var errs []error
+	for _, d := range dd {
+		w, err := s.applyMaintenanceWindow(ctx, d.id, d.deviceId, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("window %d of device %d: %w", d.id, d.deviceId, err))
+			continue
+		}
+		if w != nil {
+			changed = append(changed, *w)
+		}
+	}
+
+	return changed, errors.Join(errs...)
+}
+
+// applyMaintenanceWindow starts or ends the window windowId of the device if
+// it is due at now, and returns the window if it changed.
+func (s *service) applyMaintenanceWindow(ctx context.Context, windowId, deviceId int64, now time.Time) (*devices.MaintenanceWindow, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if errors.Is(err, devices.ErrNotExist) {
+		// The device is in the trash.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	w, err := scanMaintenance(tx.QueryRowContext(ctx, getMaintenanceForUpdate, windowId, deviceId))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var action devices.HistoryAction
+	switch {
+	case w.StartedAt == nil && !w.StartsAt.After(now) && w.EndsAt.After(now):
+		if !cur.State.CanTransitionTo(devices.Maintenance) {
+			return nil, nil
+		}
+
+		err = cur.StartMaintenance(w, now)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, startMaintenance, *w.PreviousState, w.StartedAt, w.Id)
+		if err != nil {
+			return nil, err
+		}
+		action = devices.HistoryMaintenanceStarted
+	case w.StartedAt != nil && w.EndedAt == nil && !w.EndsAt.After(now):
+		err = cur.EndMaintenance(w, now)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, endMaintenance, w.EndedAt, w.Id)
+		if err != nil {
+			return nil, err
+		}
+		action = devices.HistoryMaintenanceEnded
+	default:
+		return nil, nil
+	}
+
+	if cur.State != before.State {
+		_, err = tx.ExecContext(ctx, updateDeviceState, cur.State, cur.Id)
+		if err != nil {
+			return nil, err
+		}
+		cur.Version++
+
+		err = recordHistory(ctx, tx, action, &before, cur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
@@ -0,0 +1,334 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations holds the schema as numbered SQL files, such as
+// 0002_device_history.up.sql and 0002_device_history.down.sql. The down
+// script only undoes its own up script; a migration without one cannot be
+// reverted. Applied migrations must not be edited, schema changes go into a
+// new pair, and scripts do not use IF NOT EXISTS so that drift fails loudly.
+//
+// 0001 is the schema of databases created before migrations were
+// versioned. Such databases are adopted: 0001 is recorded without running.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the key of the advisory lock held while migrating, so
+// that replicas starting together apply each migration once.
+const migrationLock = 4_621_308_557
+
+const createMigrations = `CREATE TABLE IF NOT EXISTS migrations (
+	sequence           INTEGER PRIMARY KEY,
+	filename           TEXT NOT NULL,
+	revision           TEXT NOT NULL,
+	revision_timestamp TIMESTAMP NOT NULL
+)`
+
+const listMigrations = `SELECT sequence, filename, revision, revision_timestamp FROM migrations ORDER BY sequence`
+
+const insertMigration = `INSERT INTO migrations (sequence, filename, revision, revision_timestamp) VALUES ($1, $2, $3, $4)`
+
+const deleteMigration = `DELETE FROM migrations WHERE sequence = $1`
+
+// hasUnversionedSchema reports whether the devices table was created by hand
+// from the baseline schema, before the migrations table was used.
+const hasUnversionedSchema = `SELECT NOT EXISTS (SELECT 1 FROM migrations) AND to_regclass('devices') IS NOT NULL`
+
+// Migration is a numbered schema change.
+type Migration struct {
+	Sequence int
+	// Filename is the name of the up script.
+	Filename string
+	// Revision is the SHA-256 of the up script, as embedded or as applied.
+	Revision string
+	// AppliedAt is zero while the migration is pending.
+	AppliedAt time.Time
+	// Modified reports that the embedded up script differs from the one
+	// that was applied.
+	Modified bool
+
+	up, down string
+}
+
+// Migrator applies the embedded migrations to a database.
+type Migrator struct {
+	db *sql.DB
+}
+
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	all, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	return m.To(ctx, all[len(all)-1].Sequence)
+}
+
+// Down reverts the last applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return nil
+		}
+		return m.migrateTo(ctx, conn, applied[len(applied)-1].Sequence-1)
+	})
+}
+
+// To applies or reverts migrations until sequence is the last one applied.
+// The baseline cannot be reverted, so sequence must be at least 1.
+func (m *Migrator) To(ctx context.Context, sequence int) error {
+	if sequence < 1 {
+		return fmt.Errorf("cannot migrate to %d: the baseline cannot be reverted, the lowest sequence is 1", sequence)
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		return m.migrateTo(ctx, conn, sequence)
+	})
+}
+
+// Status lists the embedded migrations together with those recorded in the
+// database, in order.
+func (m *Migrator) Status(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, createMigrations)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	bySequence := map[int]int{}
+	for i, mg := range all {
+		bySequence[mg.Sequence] = i
+	}
+	for _, a := range applied {
+		i, ok := bySequence[a.Sequence]
+		if !ok {
+			// Applied by a newer build.
+			all = append(all, a)
+			continue
+		}
+		all[i].AppliedAt = a.AppliedAt
+		all[i].Modified = all[i].Revision != a.Revision
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Sequence < all[j].Sequence })
+
+	return all, nil
+}
+
+// locked runs fn on a single connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock)
+	if err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	// Unlock with a fresh context so a cancelled ctx still releases the lock.
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+
+	_, err = conn.ExecContext(ctx, createMigrations)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) migrateTo(ctx context.Context, conn *sql.Conn, sequence int) error {
+	all, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if sequence < 0 || sequence > 0 && !hasMigration(all, sequence) {
+		return fmt.Errorf("no migration %d", sequence)
+	}
+
+	if len(all) > 0 {
+		err = adoptBaseline(ctx, conn, all[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	done := map[int]bool{}
+	for _, a := range applied {
+		done[a.Sequence] = true
+		if a.Sequence > sequence && !hasMigration(all, a.Sequence) {
+			return fmt.Errorf("migration %s was applied by a newer build and cannot be reverted", a.Filename)
+		}
+	}
+
+	// Revert newest first, then apply oldest first.
+	for i := len(all) - 1; i >= 0; i-- {
+		mg := all[i]
+		if mg.Sequence <= sequence || !done[mg.Sequence] {
+			continue
+		}
+		if mg.down == "" {
+			return fmt.Errorf("migration %s cannot be reverted", mg.Filename)
+		}
+		err = runMigration(ctx, conn, mg.down, deleteMigration, mg.Sequence)
+		if err != nil {
+			return fmt.Errorf("reverting migration %s: %w", mg.Filename, err)
+		}
+	}
+	for _, mg := range all {
+		if mg.Sequence > sequence || done[mg.Sequence] {
+			continue
+		}
+		err = runMigration(ctx, conn, mg.up, insertMigration, mg.Sequence, mg.Filename, mg.Revision, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("applying migration %s: %w", mg.Filename, err)
+		}
+	}
+
+	return nil
+}
+
+// runMigration executes script and then record with args in one
+// transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) ([]Migration, error) {
+	rows, err := conn.QueryContext(ctx, listMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := []Migration{}
+	for rows.Next() {
+		var mg Migration
+		err = rows.Scan(&mg.Sequence, &mg.Filename, &mg.Revision, &mg.AppliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, mg)
+	}
+	return applied, rows.Err()
+}
+
+// adoptBaseline records baseline as applied on a database whose schema was
+// applied by hand.
+func adoptBaseline(ctx context.Context, conn *sql.Conn, baseline Migration) error {
+	var unversioned bool
+	err := conn.QueryRowContext(ctx, hasUnversionedSchema).Scan(&unversioned)
+	if err != nil || !unversioned {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, insertMigration, baseline.Sequence, baseline.Filename, baseline.Revision, time.Now().UTC())
+	return err
+}
+
+// loadMigrations reads the embedded migrations in order.
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	all := []Migration{}
+	for _, f := range files {
+		name := strings.TrimPrefix(f, "migrations/")
+		seq, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil || seq <= 0 {
+			return nil, fmt.Errorf("migration %s: want a numbered file name", name)
+		}
+		if len(all) > 0 && all[len(all)-1].Sequence == seq {
+			return nil, fmt.Errorf("migration %s: sequence %d is used twice", name, seq)
+		}
+
+		up, err := migrations.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		down, err := migrations.ReadFile(strings.TrimSuffix(f, ".up.sql") + ".down.sql")
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		sum := sha256.Sum256(up)
+
+		all = append(all, Migration{
+			Sequence: seq,
+			Filename: name,
+			Revision: hex.EncodeToString(sum[:]),
+			up:       string(up),
+			down:     string(down),
+		})
+	}
+	return all, nil
+}
+
+func hasMigration(all []Migration, sequence int) bool {
+	for _, mg := range all {
+		if mg.Sequence == sequence {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	all, err := loadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	for i, mg := range all {
+		assert.Equal(t, i+1, mg.Sequence, "migrations are numbered without gaps")
+		assert.NotEmpty(t, mg.up, mg.Filename)
+		assert.NotContains(t, mg.up, "IF NOT EXISTS "+"devices", mg.Filename)
+		assert.Len(t, mg.Revision, 64, mg.Filename)
+		if i == 0 {
+			assert.Empty(t, mg.down, "the baseline cannot be reverted")
+		} else {
+			assert.NotEmpty(t, mg.down, mg.Filename)
+		}
+	}
+}
+
+func TestMigratorTo_BeforeBaseline(t *testing.T) {
+	m := NewMigrator(nil)
+	for _, seq := range []int{0, -1} {
+		assert.EqualError(t, m.To(context.Background(), seq),
+			"cannot migrate to "+strconv.Itoa(seq)+": the baseline cannot be reverted, the lowest sequence is 1")
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	requireDatabase(t)
+	ctx := context.Background()
+	db, err := Open(ctx, testConfig)
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+
+	require.NoError(t, m.Up(ctx))
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, mg := range status {
+		assert.False(t, mg.AppliedAt.IsZero(), "%s is applied", mg.Filename)
+		assert.False(t, mg.Modified, mg.Filename)
+	}
+
+	// Reverting keeps the baseline devices table.
+	require.NoError(t, m.To(ctx, 1))
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	for _, mg := range status[1:] {
+		assert.True(t, mg.AppliedAt.IsZero(), "%s is reverted", mg.Filename)
+	}
+	_, err = db.ExecContext(ctx, "SELECT 1 FROM device_history")
+	assert.Error(t, err, "the device_history table is dropped")
+	assert.EqualError(t, m.Down(ctx), "migration "+status[0].Filename+" cannot be reverted")
+
+	// A database created by hand from the baseline schema is adopted, and
+	// its free-text brands are moved into the brand catalog.
+	_, err = db.ExecContext(ctx, "DELETE FROM migrations")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO devices (d_name, d_brand, d_state) VALUES
+		('iPhone', 'Apple', 0), ('iPad', ' apple', 0), ('EliteBook', 'Hewlett  Packard', 0)`)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Up(ctx))
+
+	rows, err := db.QueryContext(ctx, `SELECT d.d_name, b.name, b.slug FROM devices d JOIN brands b ON b.id = d.brand_id ORDER BY d.id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var got [][3]string
+	for rows.Next() {
+		var r [3]string
+		require.NoError(t, rows.Scan(&r[0], &r[1], &r[2]))
+		got = append(got, r)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][3]string{
+		{"iPhone", "Apple", "apple"},
+		{"iPad", "Apple", "apple"},
+		{"EliteBook", "Hewlett  Packard", "hewlett-packard"},
+	}, got)
+
+	_, err = db.ExecContext(ctx, "TRUNCATE devices, brands RESTART IDENTITY CASCADE")
+	require.NoError(t, err)
+
+	assert.EqualError(t, m.To(ctx, len(status)+1), "no migration "+strconv.Itoa(len(status)+1))
+}
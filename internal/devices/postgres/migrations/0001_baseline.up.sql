@@ -1,14 +1,11 @@
-CREATE TABLE migrations (
-	sequence           INTEGER PRIMARY KEY,
-	filename           TEXT NOT NULL,
-	revision           TEXT NOT NULL,
-	revision_timestamp TIMESTAMP NOT NULL
-);
-CREATE TABLE IF NOT EXISTS devices(
+-- The schema as it was before migrations were versioned. Databases that
+-- already have the devices table are recorded at this migration without
+-- running it.
+CREATE TABLE devices(
     id                SERIAL PRIMARY KEY,
     d_name            TEXT NOT NULL,
     d_brand           TEXT NOT NULL,
     d_state           INTEGER NOT NULL,
     created_at        TIMESTAMP WITH TIME ZONE DEFAULT (now())
  -- updated_at        TIMESTAMP NOT NULL
-);
\ No newline at end of file
+);
@@ -0,0 +1,12 @@
+CREATE TABLE device_history(
+    id                BIGSERIAL PRIMARY KEY,
+    device_id         INTEGER NOT NULL, -- no foreign key, history outlives the device
+    action            TEXT NOT NULL,
+    actor             TEXT NOT NULL DEFAULT '',
+    before            JSONB,
+    after             JSONB,
+    changed_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX device_history_device_id_changed_at_idx
+    ON device_history (device_id, changed_at);
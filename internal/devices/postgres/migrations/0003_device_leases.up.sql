@@ -0,0 +1,15 @@
+CREATE TABLE device_leases(
+    id                 BIGSERIAL PRIMARY KEY,
+    device_id          INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    holder             TEXT NOT NULL,
+    checked_out_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now()),
+    expected_return_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    returned_at        TIMESTAMP WITH TIME ZONE
+);
+
+-- A device can only have one active lease at a time.
+CREATE UNIQUE INDEX device_leases_active_idx
+    ON device_leases (device_id) WHERE returned_at IS NULL;
+
+CREATE INDEX device_leases_expected_return_at_idx
+    ON device_leases (expected_return_at) WHERE returned_at IS NULL;
@@ -0,0 +1,16 @@
+-- The extension is database-wide and may already be installed, so it is
+-- neither required to be missing here nor dropped by the down script.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE device_reservations(
+    id                BIGSERIAL PRIMARY KEY,
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    holder            TEXT NOT NULL,
+    starts_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at           TIMESTAMP WITH TIME ZONE NOT NULL,
+    during            TSTZRANGE GENERATED ALWAYS AS (tstzrange(starts_at, ends_at, '[)')) STORED,
+    created_at        TIMESTAMP WITH TIME ZONE DEFAULT (now()),
+    CHECK (starts_at < ends_at),
+    -- A device cannot be booked twice for overlapping windows.
+    EXCLUDE USING gist (device_id WITH =, during WITH &&)
+);
@@ -0,0 +1 @@
+ALTER TABLE devices ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
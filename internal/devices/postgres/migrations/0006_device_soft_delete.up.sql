@@ -0,0 +1,4 @@
+ALTER TABLE devices ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX devices_deleted_at_idx
+    ON devices (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE devices ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX devices_attributes_idx
+    ON devices USING gin (attributes jsonb_path_ops);
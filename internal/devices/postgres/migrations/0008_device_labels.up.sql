@@ -0,0 +1,9 @@
+CREATE TABLE device_labels(
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    key               TEXT NOT NULL,
+    value             TEXT NOT NULL,
+    PRIMARY KEY (device_id, key)
+);
+
+CREATE INDEX device_labels_key_value_idx
+    ON device_labels (key, value);
@@ -0,0 +1,11 @@
+ALTER TABLE devices ADD COLUMN d_brand TEXT;
+
+UPDATE devices d SET d_brand = b.name
+FROM brands b
+WHERE b.id = d.brand_id;
+
+ALTER TABLE devices ALTER COLUMN d_brand SET NOT NULL;
+ALTER TABLE devices DROP COLUMN brand_id;
+
+DROP TABLE brand_aliases;
+DROP TABLE brands;
@@ -0,0 +1,36 @@
+CREATE TABLE brands(
+    id                SERIAL PRIMARY KEY,
+    name              TEXT NOT NULL,
+    slug              TEXT NOT NULL UNIQUE,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE brand_aliases(
+    slug              TEXT PRIMARY KEY,
+    brand_id          INTEGER NOT NULL REFERENCES brands (id) ON DELETE CASCADE
+);
+
+CREATE INDEX brand_aliases_brand_id_idx
+    ON brand_aliases (brand_id);
+
+-- Every distinct free-text brand becomes a catalog entry. Spellings with
+-- the same slug share one brand, named after the first spelling in sort
+-- order. The slug follows devices.BrandSlug: lower case, with runs of other
+-- characters between letters and digits turned into a single dash.
+INSERT INTO brands (name, slug)
+SELECT min(btrim(d_brand)), COALESCE(NULLIF(btrim(regexp_replace(lower(btrim(d_brand)), '[^[:alnum:]]+', '-', 'g'), '-'), ''), 'unknown')
+FROM devices
+GROUP BY 2
+ORDER BY 2;
+
+ALTER TABLE devices ADD COLUMN brand_id INTEGER REFERENCES brands (id);
+
+UPDATE devices d SET brand_id = b.id
+FROM brands b
+WHERE b.slug = COALESCE(NULLIF(btrim(regexp_replace(lower(btrim(d.d_brand)), '[^[:alnum:]]+', '-', 'g'), '-'), ''), 'unknown');
+
+ALTER TABLE devices ALTER COLUMN brand_id SET NOT NULL;
+ALTER TABLE devices DROP COLUMN d_brand;
+
+CREATE INDEX devices_brand_id_idx
+    ON devices (brand_id);
@@ -0,0 +1,3 @@
+ALTER TABLE devices DROP COLUMN model_id;
+
+DROP TABLE models;
@@ -0,0 +1,13 @@
+CREATE TABLE models(
+    id                SERIAL PRIMARY KEY,
+    brand_id          INTEGER NOT NULL REFERENCES brands (id) ON DELETE CASCADE,
+    name              TEXT NOT NULL,
+    specs             JSONB NOT NULL DEFAULT '{}',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now()),
+    UNIQUE (brand_id, name)
+);
+
+ALTER TABLE devices ADD COLUMN model_id INTEGER REFERENCES models (id);
+
+CREATE INDEX devices_model_id_idx
+    ON devices (model_id);
@@ -0,0 +1,3 @@
+ALTER TABLE devices
+    DROP COLUMN serial_number,
+    DROP COLUMN asset_tag;
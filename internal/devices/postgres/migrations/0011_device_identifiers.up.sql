@@ -0,0 +1,11 @@
+ALTER TABLE devices
+    ADD COLUMN serial_number TEXT,
+    ADD COLUMN asset_tag     TEXT;
+
+-- Devices in the trash keep their identifiers but no longer reserve them,
+-- so a device deleted by mistake can be registered again.
+CREATE UNIQUE INDEX devices_serial_number_key
+    ON devices (serial_number) WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX devices_asset_tag_key
+    ON devices (asset_tag) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE devices DROP COLUMN location_id;
+
+DROP TABLE locations;
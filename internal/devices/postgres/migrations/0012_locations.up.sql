@@ -0,0 +1,18 @@
+CREATE TABLE locations(
+    id                SERIAL PRIMARY KEY,
+    parent_id         INTEGER REFERENCES locations (id),
+    kind              TEXT NOT NULL,
+    name              TEXT NOT NULL,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now()),
+    UNIQUE (parent_id, name)
+);
+
+-- UNIQUE (parent_id, name) treats every NULL parent as distinct, so the
+-- names of top-level locations are kept unique by their own index.
+CREATE UNIQUE INDEX locations_root_name_idx
+    ON locations (name) WHERE parent_id IS NULL;
+
+ALTER TABLE devices ADD COLUMN location_id INTEGER REFERENCES locations (id);
+
+CREATE INDEX devices_location_id_idx
+    ON devices (location_id);
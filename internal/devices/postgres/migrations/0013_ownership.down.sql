@@ -0,0 +1,8 @@
+DROP TABLE ownership_transfers;
+
+ALTER TABLE devices
+    DROP COLUMN owner_id,
+    DROP COLUMN assignee_id;
+
+DROP TABLE teams;
+DROP TABLE users;
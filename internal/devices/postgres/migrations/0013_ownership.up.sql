@@ -0,0 +1,44 @@
+CREATE TABLE users(
+    id                SERIAL PRIMARY KEY,
+    name              TEXT NOT NULL,
+    email             TEXT UNIQUE,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE teams(
+    id                SERIAL PRIMARY KEY,
+    name              TEXT NOT NULL UNIQUE,
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE devices
+    ADD COLUMN owner_id    INTEGER REFERENCES teams (id),
+    ADD COLUMN assignee_id INTEGER REFERENCES users (id);
+
+CREATE INDEX devices_owner_id_idx
+    ON devices (owner_id);
+
+CREATE INDEX devices_assignee_id_idx
+    ON devices (assignee_id);
+
+CREATE TABLE ownership_transfers(
+    id                BIGSERIAL PRIMARY KEY,
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    from_team_id      INTEGER REFERENCES teams (id),
+    to_team_id        INTEGER NOT NULL REFERENCES teams (id),
+    status            TEXT NOT NULL,
+    requested_by      TEXT NOT NULL DEFAULT '',
+    requested_at      TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_by       TEXT,
+    resolved_at       TIMESTAMP WITH TIME ZONE
+);
+
+-- At most one pending transfer per device.
+CREATE UNIQUE INDEX ownership_transfers_pending_idx
+    ON ownership_transfers (device_id) WHERE status = 'pending';
+
+CREATE INDEX ownership_transfers_from_team_id_idx
+    ON ownership_transfers (from_team_id);
+
+CREATE INDEX ownership_transfers_to_team_id_idx
+    ON ownership_transfers (to_team_id);
@@ -0,0 +1,19 @@
+CREATE TABLE maintenance_windows(
+    id                BIGSERIAL PRIMARY KEY,
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    starts_at         TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at           TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason            TEXT NOT NULL,
+    technician        TEXT NOT NULL,
+    previous_state    INTEGER,
+    started_at        TIMESTAMP WITH TIME ZONE,
+    ended_at          TIMESTAMP WITH TIME ZONE,
+    during            TSTZRANGE GENERATED ALWAYS AS (tstzrange(starts_at, ends_at, '[)')) STORED,
+    created_at        TIMESTAMP WITH TIME ZONE DEFAULT (now()),
+    CHECK (starts_at < ends_at),
+    -- A device cannot have overlapping maintenance windows.
+    EXCLUDE USING gist (device_id WITH =, during WITH &&)
+);
+
+CREATE INDEX maintenance_windows_due_idx
+    ON maintenance_windows (starts_at) WHERE ended_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE devices
+    DROP COLUMN purchase_date,
+    DROP COLUMN warranty_expires_on,
+    DROP COLUMN end_of_life_on;
@@ -0,0 +1,10 @@
+ALTER TABLE devices
+    ADD COLUMN purchase_date       DATE,
+    ADD COLUMN warranty_expires_on DATE CHECK (warranty_expires_on >= purchase_date),
+    ADD COLUMN end_of_life_on      DATE CHECK (end_of_life_on > purchase_date);
+
+CREATE INDEX devices_warranty_expires_on_idx
+    ON devices (warranty_expires_on) WHERE warranty_expires_on IS NOT NULL;
+
+CREATE INDEX devices_end_of_life_on_idx
+    ON devices (end_of_life_on) WHERE end_of_life_on IS NOT NULL;
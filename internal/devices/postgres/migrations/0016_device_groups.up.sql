@@ -0,0 +1,16 @@
+CREATE TABLE device_groups(
+    id                SERIAL PRIMARY KEY,
+    name              TEXT NOT NULL UNIQUE,
+    description       TEXT NOT NULL DEFAULT '',
+    filter            TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE device_group_members(
+    group_id          INTEGER NOT NULL REFERENCES device_groups (id) ON DELETE CASCADE,
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, device_id)
+);
+
+CREATE INDEX device_group_members_device_id_idx
+    ON device_group_members (device_id);
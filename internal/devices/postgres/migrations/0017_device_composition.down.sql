@@ -0,0 +1,3 @@
+ALTER TABLE device_leases DROP COLUMN cascaded_from;
+
+ALTER TABLE devices DROP COLUMN parent_id;
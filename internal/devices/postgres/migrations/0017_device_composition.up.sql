@@ -0,0 +1,11 @@
+ALTER TABLE devices
+    ADD COLUMN parent_id INTEGER REFERENCES devices (id) ON DELETE SET NULL CHECK (parent_id <> id);
+
+CREATE INDEX devices_parent_id_idx
+    ON devices (parent_id) WHERE parent_id IS NOT NULL;
+
+ALTER TABLE device_leases
+    ADD COLUMN cascaded_from BIGINT REFERENCES device_leases (id) ON DELETE SET NULL;
+
+CREATE INDEX device_leases_cascaded_from_idx
+    ON device_leases (cascaded_from) WHERE returned_at IS NULL;
@@ -0,0 +1,8 @@
+CREATE TABLE team_members(
+    team_id           INTEGER NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+    user_id           INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX team_members_user_id_idx
+    ON team_members (user_id);
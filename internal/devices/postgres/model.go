@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"strings"
+)
+
+const selectModels = `SELECT m.id, m.brand_id, b.name, m.name, m.specs, m.created_at
+FROM models m
+JOIN brands b ON b.id = m.brand_id
+`
+
+const getModelById = selectModels + `WHERE m.id = $1`
+
+const getModelsByBrand = selectModels + `WHERE m.brand_id = $1 ORDER BY m.name`
+
+func scanModel(row rowScanner) (*devices.Model, error) {
+	var (
+		m     devices.Model
+		specs []byte
+	)
+
+	err := row.Scan(&m.Id, &m.BrandId, &m.Brand, &m.Name, &specs, &m.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.Specs, err = unmarshalAttributes(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// resolveDeviceBrand resolves the brand of a device write and checks that
+// modelId, if set, is a model of that brand. A device given only a model
+// takes the brand of the model.
+func resolveDeviceBrand(ctx context.Context, tx *sql.Tx, brandId int64, brand string, modelId int64) (*devices.Brand, error) {
+	var m *devices.Model
+	if modelId != 0 {
+		var err error
+		m, err = scanModel(tx.QueryRowContext(ctx, getModelById, modelId))
+		if errors.Is(err, devices.ErrNotExist) {
+			return nil, devices.ErrUnknownModel
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if brandId == 0 && strings.TrimSpace(brand) == "" {
+			brandId = m.BrandId
+		}
+	}
+
+	b, err := resolveBrand(ctx, tx, brandId, brand)
+	if err != nil {
+		return nil, err
+	}
+
+	if m != nil && m.BrandId != b.Id {
+		return nil, devices.ErrUnknownModel
+	}
+
+	return b, nil
+}
+
+const createModel = `INSERT INTO models (brand_id, name, specs, created_at) VALUES ($1, $2, $3, NOW()) RETURNING id`
+
+func (s *service) CreateModel(ctx context.Context, brandId int64, cm devices.CreateModel) (*devices.Model, error) {
+	name := strings.TrimSpace(cm.Name)
+	if name == "" {
+		return nil, devices.ErrInvalidModel
+	}
+
+	specs, err := marshalAttributes(cm.Specs)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, createModel, brandId, name, specs).Scan(&id)
+	switch pgErrorCode(err) {
+	case uniqueViolation:
+		return nil, devices.ErrDuplicate
+	case foreignKeyViolation:
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetModel(ctx, id)
+}
+
+func (s *service) GetModel(ctx context.Context, id int64) (*devices.Model, error) {
+	return scanModel(s.db.QueryRowContext(ctx, getModelById, id))
+}
+
+func (s *service) Models(ctx context.Context, brandId int64) ([]devices.Model, error) {
+	rows, err := s.db.QueryContext(ctx, getModelsByBrand, brandId)
+	if err != nil {
+		return []devices.Model{}, err
+	}
+	defer rows.Close()
+
+	mm := []devices.Model{}
+	for rows.Next() {
+		m, err := scanModel(rows)
+		if err != nil {
+			return []devices.Model{}, err
+		}
+		mm = append(mm, *m)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Model{}, err
+	}
+
+	return mm, nil
+}
+
+const deleteModel = `DELETE FROM models WHERE id = $1`
+
+// DeleteModel removes a model that no device references, including devices
+// in the trash.
+func (s *service) DeleteModel(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, deleteModel, id)
+	if pgErrorCode(err) == foreignKeyViolation {
+		return devices.ErrModelInUse
+	}
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
+
+const getDevicesByModel = selectDevices + `WHERE d.deleted_at IS NULL AND d.model_id = $1 ORDER BY d.id`
+
+func (s *service) GetByModel(ctx context.Context, modelId int64) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByModel, modelId)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// modelInventory returns one row per model and device state. Models without
+// devices yield a single row with a NULL state and a zero count.
+const modelInventory = `SELECT m.id, m.name, b.id, b.name, d.d_state, count(d.id)
+FROM models m
+JOIN brands b ON b.id = m.brand_id
+LEFT JOIN devices d ON d.model_id = m.id AND d.deleted_at IS NULL
+WHERE ($1::integer = 0 OR m.brand_id = $1)
+GROUP BY m.id, m.name, b.id, b.name, d.d_state
+ORDER BY b.name, m.name, m.id`
+
+func (s *service) Inventory(ctx context.Context, brandId int64) ([]devices.ModelInventory, error) {
+	rows, err := s.db.QueryContext(ctx, modelInventory, brandId)
+	if err != nil {
+		return []devices.ModelInventory{}, err
+	}
+	defer rows.Close()
+
+	inv := []devices.ModelInventory{}
+	for rows.Next() {
+		var (
+			mi    devices.ModelInventory
+			state sql.NullInt64
+			n     int64
+		)
+
+		err := rows.Scan(&mi.ModelId, &mi.Model, &mi.BrandId, &mi.Brand, &state, &n)
+		if err != nil {
+			return []devices.ModelInventory{}, err
+		}
+
+		if len(inv) == 0 || inv[len(inv)-1].ModelId != mi.ModelId {
+			mi.ByState = map[devices.DeviceState]int64{}
+			inv = append(inv, mi)
+		}
+
+		cur := &inv[len(inv)-1]
+		if state.Valid {
+			cur.ByState[devices.DeviceState(state.Int64)] = n
+			cur.Total += n
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.ModelInventory{}, err
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"errors"
+	"time"
+)
+
+const assignDevice = `UPDATE devices SET assignee_id = $1, version = version + 1 WHERE id = $2`
+
+// AssignDevice sets or clears the assignee of a device and records the
+// change in the device history.
+func (s *service) AssignDevice(ctx context.Context, deviceId int64, a devices.Assignment, version int64) (*devices.Device, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.UserId != 0 {
+		_, err = getUser(ctx, tx, a.UserId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, assignDevice, nullId(a.UserId), cur.Id)
+	if err != nil {
+		return nil, err
+	}
+	cur.AssigneeId = a.UserId
+	cur.Version++
+
+	err = recordHistory(ctx, tx, devices.HistoryAssigned, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+const getDevicesByOwner = selectDevices + `WHERE d.deleted_at IS NULL AND d.owner_id = $1 ORDER BY d.id`
+
+func (s *service) GetByOwner(ctx context.Context, teamId int64) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByOwner, teamId)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+const getDevicesByAssignee = selectDevices + `WHERE d.deleted_at IS NULL AND d.assignee_id = $1 ORDER BY d.id`
+
+func (s *service) GetByAssignee(ctx context.Context, userId int64) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByAssignee, userId)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+const selectTransfers = `SELECT id, device_id, from_team_id, to_team_id, status, requested_by, requested_at, resolved_by, resolved_at
+FROM ownership_transfers
+`
+
+func scanTransfer(row rowScanner) (*devices.Transfer, error) {
+	var (
+		t          devices.Transfer
+		fromTeamId sql.NullInt64
+		resolvedBy sql.NullString
+	)
+
+	err := row.Scan(&t.Id, &t.DeviceId, &fromTeamId, &t.ToTeamId, &t.Status, &t.RequestedBy, &t.RequestedAt, &resolvedBy, &t.ResolvedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, devices.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.FromTeamId = fromTeamId.Int64
+	t.ResolvedBy = resolvedBy.String
+
+	return &t, nil
+}
+
+const insertTransfer = `INSERT INTO ownership_transfers (
+  device_id,
+  from_team_id,
+  to_team_id,
+  status,
+  requested_by,
+  requested_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING id`
+
+// RequestTransfer offers a device to another team. Only one transfer of a
+// device can be pending at a time, which the partial unique index on
+// ownership_transfers enforces.
+func (s *service) RequestTransfer(ctx context.Context, deviceId int64, ct devices.CreateTransfer) (*devices.Transfer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := cur.RequestTransfer(ct, devices.ActorFromContext(ctx), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = getTeam(ctx, tx, t.ToTeamId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, insertTransfer,
+		t.DeviceId, nullId(t.FromTeamId), t.ToTeamId, t.Status, t.RequestedBy, t.RequestedAt,
+	).Scan(&t.Id)
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, devices.ErrTransferPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+const getTransfers = selectTransfers + `WHERE ($1::integer = 0 OR device_id = $1)
+  AND ($2::integer = 0 OR from_team_id = $2 OR to_team_id = $2)
+  AND ($3::text = '' OR status = $3)
+ORDER BY requested_at DESC, id DESC`
+
+func (s *service) Transfers(ctx context.Context, f devices.TransferFilter) ([]devices.Transfer, error) {
+	rows, err := s.db.QueryContext(ctx, getTransfers, f.DeviceId, f.TeamId, string(f.Status))
+	if err != nil {
+		return []devices.Transfer{}, err
+	}
+	defer rows.Close()
+
+	tt := []devices.Transfer{}
+	for rows.Next() {
+		t, err := scanTransfer(rows)
+		if err != nil {
+			return []devices.Transfer{}, err
+		}
+		tt = append(tt, *t)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Transfer{}, err
+	}
+
+	return tt, nil
+}
+
+const getTransferForUpdate = selectTransfers + `WHERE id = $1 FOR UPDATE`
+
+const resolveTransfer = `UPDATE ownership_transfers SET status = $1, resolved_by = $2, resolved_at = $3 WHERE id = $4`
+
+const transferDevice = `UPDATE devices SET owner_id = $1, version = version + 1 WHERE id = $2`
+
+// ResolveTransfer accepts, rejects or cancels a pending transfer on behalf
+// of a team the acting user is a member of. Accepting moves the device to
+// the receiving team and records the change in the device history. A
+// transfer whose device changed owner since it was requested can no longer
+// be accepted.
+func (s *service) ResolveTransfer(ctx context.Context, transferId int64, tr devices.TransferResolution, status devices.TransferStatus) (*devices.Transfer, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTransfer(tx.QueryRowContext(ctx, getTransferForUpdate, transferId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.Resolve(status, tr, devices.ActorFromContext(ctx), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkTeamMember(ctx, tx, tr.TeamId, devices.UserFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Status == devices.TransferAccepted {
+		cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, t.DeviceId))
+		if err != nil {
+			return nil, err
+		}
+		before := *cur
+
+		if cur.OwnerId != t.FromTeamId {
+			return nil, devices.ErrTransferResolved
+		}
+
+		_, err = tx.ExecContext(ctx, transferDevice, t.ToTeamId, cur.Id)
+		if err != nil {
+			return nil, err
+		}
+		cur.OwnerId = t.ToTeamId
+		cur.Version++
+
+		err = recordHistory(ctx, tx, devices.HistoryTransferred, &before, cur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, resolveTransfer, t.Status, t.ResolvedBy, t.ResolvedAt, t.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
@@ -7,67 +7,148 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
+	"strings"
 	"time"
-
-	_ "github.com/jackc/pgx/v5/stdlib"
-)
-
-var (
-	database   = os.Getenv("DB_DATABASE")
-	password   = os.Getenv("DB_PASSWORD")
-	username   = os.Getenv("DB_USERNAME")
-	port       = os.Getenv("DB_PORT")
-	host       = os.Getenv("DB_HOST")
-	schema     = os.Getenv("DB_SCHEMA")
-	dbInstance *service
 )
 
 type service struct {
 	db *sql.DB
+	// database is the name of the database, for logging.
+	database string
 }
 
-func NewRepository() devices.Repository {
-	// Reuse Connection
-	if dbInstance != nil {
-		return dbInstance
+// New opens the repository on the database described by cfg, applying
+// pending migrations first when cfg.AutoMigrate is set.
+func New(ctx context.Context, cfg Config) (devices.Repository, error) {
+	cc, err := cfg.connConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
-	db, err := sql.Open("pgx", connStr)
+	db, err := Open(ctx, cfg)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	dbInstance = &service{
-		db: db,
+
+	if cfg.AutoMigrate {
+		err = NewMigrator(db).Up(ctx)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("migrating database: %w", err)
+		}
 	}
 
-	return dbInstance
+	return &service{db: db, database: cc.Database}, nil
 }
 
 const createDevice = `-- name: CreateDevice :one
 INSERT INTO devices (
   d_name,
-  d_brand,
+  brand_id,
   d_state,
+  attributes,
+  model_id,
+  serial_number,
+  asset_tag,
+  location_id,
+  owner_id,
+  assignee_id,
+  purchase_date,
+  warranty_expires_on,
+  end_of_life_on,
   created_at
 ) VALUES (
-  $1, $2, $3, NOW()
-) RETURNING d_name, d_brand, d_state, created_at
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW()
+) RETURNING id
 `
 
-func (s *service) Create(ctx context.Context, cd devices.CreateDevice) (*devices.Device, error) {
-	row := s.db.QueryRowContext(ctx, createDevice, cd.Name, cd.Brand, cd.State)
+// selectDevices is the common projection for reading devices together with
+// their brand and active lease. Queries append their own WHERE clause, which must
+// filter on d.deleted_at to hide or select soft-deleted devices.
+const selectDevices = `SELECT d.id, d.d_name, b.name, d.d_state, d.created_at, d.brand_id, d.model_id, d.serial_number, d.asset_tag, d.location_id, d.owner_id, d.assignee_id, d.parent_id,
+  d.purchase_date, d.warranty_expires_on, d.end_of_life_on, d.version, d.deleted_at, d.attributes,
+  (SELECT jsonb_object_agg(dl.key, dl.value) FROM device_labels dl WHERE dl.device_id = d.id),
+  l.id, l.holder, l.checked_out_at, l.expected_return_at, l.cascaded_from
+FROM devices d
+JOIN brands b ON b.id = d.brand_id
+LEFT JOIN device_leases l ON l.device_id = d.id AND l.returned_at IS NULL
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanDevice(row rowScanner) (*devices.Device, error) {
+	var (
+		d          devices.Device
+		l          nullLease
+		modelId    sql.NullInt64
+		serial     sql.NullString
+		tag        sql.NullString
+		locationId sql.NullInt64
+		ownerId    sql.NullInt64
+		assigneeId sql.NullInt64
+		parentId   sql.NullInt64
+		attrs      []byte
+		labels     []byte
+	)
 
-	var d devices.Device
 	err := row.Scan(
 		&d.Id,
 		&d.Name,
 		&d.Brand,
 		&d.State,
 		&d.CreatedAt,
+		&d.BrandId,
+		&modelId,
+		&serial,
+		&tag,
+		&locationId,
+		&ownerId,
+		&assigneeId,
+		&parentId,
+		&d.PurchaseDate,
+		&d.WarrantyExpiresOn,
+		&d.EndOfLifeOn,
+		&d.Version,
+		&d.DeletedAt,
+		&attrs,
+		&labels,
+		&l.id,
+		&l.holder,
+		&l.checkedOutAt,
+		&l.expectedReturnAt,
+		&l.cascadedFrom,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &devices.Device{}, devices.ErrNotExist
+	}
+	if err != nil {
+		return &devices.Device{}, err
+	}
+	d.Lease = l.lease(d.Id)
+	d.ModelId = modelId.Int64
+	d.SerialNumber = serial.String
+	d.AssetTag = tag.String
+	d.LocationId = locationId.Int64
+	d.OwnerId = ownerId.Int64
+	d.AssigneeId = assigneeId.Int64
+	d.ParentId = parentId.Int64
+
+	d.Attributes, err = unmarshalAttributes(attrs)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	d.Labels, err = unmarshalLabels(labels)
 	if err != nil {
 		return &devices.Device{}, err
 	}
@@ -75,144 +156,387 @@ func (s *service) Create(ctx context.Context, cd devices.CreateDevice) (*devices
 	return &d, nil
 }
 
-const getDeviceById = `SELECT id, d_name, d_brand, d_state, created_at FROM devices
-WHERE id = $1 LIMIT 1`
-
-func (s *service) GetById(ctx context.Context, id int64) (*devices.Device, error) {
-	var d devices.Device
+func scanDevices(rows *sql.Rows) ([]devices.Device, error) {
+	var dd []devices.Device
 
-	row := s.db.QueryRowContext(ctx, getDeviceById, id)
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return []devices.Device{}, err
+		}
+		dd = append(dd, *d)
+	}
 
-	err := row.Scan(
-		&d.Id,
-		&d.Name,
-		&d.Brand,
-		&d.State,
-		&d.CreatedAt,
-	)
+	err := rows.Err()
 	if err != nil {
-		return &devices.Device{}, err
+		return []devices.Device{}, err
 	}
 
-	return &d, nil
+	return dd, nil
 }
 
-const getDevicesByBrand = `SELECT id, d_name, d_brand, d_state, created_at FROM devices
-WHERE d_brand = $1`
-
-func (s *service) GetByBrand(ctx context.Context, brand string) ([]devices.Device, error) {
-	var dd []devices.Device
-
-	rows, err := s.db.QueryContext(ctx, getDevicesByBrand, brand)
+// queryIds runs a query selecting a single id column.
+func queryIds(ctx context.Context, q querier, query string, args ...any) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var ids []int64
 	for rows.Next() {
-		var d devices.Device
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// nullId maps the zero id of an optional reference to NULL.
+func nullId(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// nullString maps an empty optional value to NULL, so that unique
+// constraints only apply to devices that have the value.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
 
-		err = rows.Scan(&d)
+func (s *service) Create(ctx context.Context, cd devices.CreateDevice) (*devices.Device, error) {
+	nd := devices.NewDevice(cd.Name, cd.Brand)
+	nd.SerialNumber = strings.TrimSpace(cd.SerialNumber)
+	nd.AssetTag = strings.TrimSpace(cd.AssetTag)
+	err := nd.ChangeDeviceState(cd.State)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = devices.ValidateLabels(cd.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = cd.ValidateDates()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+	defer tx.Rollback()
+
+	attrs, err := marshalAttributes(cd.Attributes)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	b, err := resolveDeviceBrand(ctx, tx, cd.BrandId, cd.Brand, cd.ModelId)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	if cd.LocationId != 0 {
+		_, err = getLocation(ctx, tx, cd.LocationId)
 		if err != nil {
-			return []devices.Device{}, err
+			return &devices.Device{}, err
 		}
+	}
 
-		err = rows.Err()
+	if cd.OwnerId != 0 {
+		_, err = getTeam(ctx, tx, cd.OwnerId)
 		if err != nil {
-			return []devices.Device{}, err
+			return &devices.Device{}, err
 		}
-		dd = append(dd, d)
 	}
 
-	return dd, nil
+	if cd.AssigneeId != 0 {
+		_, err = getUser(ctx, tx, cd.AssigneeId)
+		if err != nil {
+			return &devices.Device{}, err
+		}
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, createDevice,
+		nd.Name, b.Id, nd.State, attrs, nullId(cd.ModelId), nullString(nd.SerialNumber), nullString(nd.AssetTag),
+		nullId(cd.LocationId), nullId(cd.OwnerId), nullId(cd.AssigneeId),
+		cd.PurchaseDate, cd.WarrantyExpiresOn, cd.EndOfLifeOn,
+	).Scan(&id)
+	if err != nil {
+		return &devices.Device{}, deviceWriteError(err, nd)
+	}
+
+	err = setLabels(ctx, tx, id, cd.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	d, err := scanDevice(tx.QueryRowContext(ctx, getDeviceById, id))
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = recordHistory(ctx, tx, devices.HistoryCreated, nil, d)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	return d, nil
 }
 
-const getDevicesByState = `SELECT id, d_name, d_brand, d_state, created_at FROM devices
-WHERE d_state = $1`
+const getDeviceById = selectDevices + `WHERE d.deleted_at IS NULL AND d.id = $1 LIMIT 1`
 
-func (s *service) GetByState(ctx context.Context, state devices.DeviceState) ([]devices.Device, error) {
-	var dd []devices.Device
+func (s *service) GetById(ctx context.Context, id int64) (*devices.Device, error) {
+	row := s.db.QueryRowContext(ctx, getDeviceById, id)
+
+	return scanDevice(row)
+}
 
-	rows, err := s.db.QueryContext(ctx, getDevicesByBrand, int(state))
+const getDevicesByBrand = selectDevices + `WHERE d.deleted_at IS NULL AND d.brand_id IN (` + resolveBrandIds + `) ORDER BY d.id`
+
+// GetByBrand lists the devices of a brand given by id, name, slug or alias.
+func (s *service) GetByBrand(ctx context.Context, brand string) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByBrand, strings.TrimSpace(brand), devices.BrandSlug(brand))
 	if err != nil {
-		return []devices.Device{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var d devices.Device
+	return scanDevices(rows)
+}
 
-		err = rows.Scan(&d)
-		if err != nil {
-			return []devices.Device{}, err
-		}
+const getDeviceByAssetTag = selectDevices + `WHERE d.deleted_at IS NULL AND d.asset_tag = $1 LIMIT 1`
 
-		err = rows.Err()
-		if err != nil {
-			return []devices.Device{}, err
-		}
-		dd = append(dd, d)
+// GetByAssetTag looks a device up by the asset tag printed on its label.
+func (s *service) GetByAssetTag(ctx context.Context, tag string) (*devices.Device, error) {
+	row := s.db.QueryRowContext(ctx, getDeviceByAssetTag, strings.TrimSpace(tag))
+
+	return scanDevice(row)
+}
+
+const getDevicesByState = selectDevices + `WHERE d.deleted_at IS NULL AND d.d_state = $1 ORDER BY d.id`
+
+func (s *service) GetByState(ctx context.Context, state devices.DeviceState) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getDevicesByState, int(state))
+	if err != nil {
+		return []devices.Device{}, err
 	}
+	defer rows.Close()
 
-	return dd, nil
+	return scanDevices(rows)
 }
 
-const getAllDevices = `SELECT * FROM devices`
+const getAllDevices = selectDevices + `WHERE d.deleted_at IS NULL ORDER BY d.id`
 
 func (s *service) All(ctx context.Context) ([]devices.Device, error) {
-	var dd []devices.Device
-
 	rows, err := s.db.QueryContext(ctx, getAllDevices)
 	if err != nil {
 		return []devices.Device{}, err
 	}
 	defer rows.Close()
 
-	for rows.Next() {
-		var d devices.Device
+	return scanDevices(rows)
+}
+
+const getDeviceForUpdate = selectDevices + `WHERE d.deleted_at IS NULL AND d.id = $1 FOR UPDATE OF d`
+
+const updateDevice = `UPDATE devices SET
+	d_name = $1, brand_id = $2, d_state = $3, attributes = $4, model_id = $5,
+	serial_number = $6, asset_tag = $7,
+	purchase_date = $8, warranty_expires_on = $9, end_of_life_on = $10, version = version + 1
+	WHERE
+	id = $11;`
+
+const updateDeviceState = `UPDATE devices SET d_state = $1, version = version + 1 WHERE id = $2`
+
+// Update applies the name, brand, model, identifiers, lifecycle dates,
+// state, attributes and labels of d to the stored device. The current row is
+// locked while the change is checked against the device state machine, so
+// concurrent updates cannot skip a transition. The change is recorded in the
+// device history within the same transaction. The location, owner,
+// assignee and parent are left as is; they change through moves, transfers,
+// assignments and attaching the device. The state of a part cannot change
+// while its parent is in use.
+func (s *service) Update(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, d.Id))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
 
-		err = rows.Scan(&d)
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.Name != d.Name {
+		err = cur.ChangeDeviceName(d.Name)
 		if err != nil {
-			return []devices.Device{}, err
+			return nil, err
 		}
+	}
 
-		err = rows.Err()
+	b, err := resolveDeviceBrand(ctx, tx, d.BrandId, d.Brand, d.ModelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.BrandId != b.Id {
+		err = cur.ChangeDeviceBrand(b.Name)
 		if err != nil {
-			return []devices.Device{}, err
+			return nil, err
 		}
-		dd = append(dd, d)
+		cur.BrandId = b.Id
 	}
+	cur.ModelId = d.ModelId
+	cur.SerialNumber = strings.TrimSpace(d.SerialNumber)
+	cur.AssetTag = strings.TrimSpace(d.AssetTag)
 
-	return dd, nil
-}
+	if cur.State != d.State {
+		err = checkParentState(ctx, tx, cur)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-const updateDevice = `UPDATE devices SET
-	d_name = $1, d_brand = $2, d_state = $3
-	WHERE
-	id = $4;`
+	err = cur.ChangeDeviceState(d.State)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *service) Update(ctx context.Context, d devices.Device) (sql.Result, error) {
-	if d.IsDeviceInUse() {
-		return nil, errors.New("cannot update device while in use state")
+	err = devices.ValidateLabels(d.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.ValidateDates()
+	if err != nil {
+		return nil, err
 	}
+	cur.Lifecycle = d.Lifecycle
 
-	result, err := s.db.Exec(updateDevice, d.Name, d.Brand, d.State)
+	cur.Attributes = d.Attributes
+	attrs, err := marshalAttributes(cur.Attributes)
 	if err != nil {
 		return nil, err
 	}
 
+	result, err := tx.ExecContext(ctx, updateDevice,
+		cur.Name, cur.BrandId, cur.State, attrs, nullId(cur.ModelId), nullString(cur.SerialNumber), nullString(cur.AssetTag),
+		cur.PurchaseDate, cur.WarrantyExpiresOn, cur.EndOfLifeOn, cur.Id,
+	)
+	if err != nil {
+		return nil, deviceWriteError(err, cur)
+	}
+	cur.Version++
+
+	_, err = tx.ExecContext(ctx, deleteAllLabels, cur.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setLabels(ctx, tx, cur.Id, d.Labels)
+	if err != nil {
+		return nil, err
+	}
+	cur.Labels = d.Labels
+
+	// Moving a checked out device out of InUse by hand ends its lease.
+	if before.Lease != nil && !cur.IsDeviceInUse() {
+		_, err = tx.ExecContext(ctx, returnLease, time.Now(), before.Lease.Id)
+		if err != nil {
+			return nil, err
+		}
+		cur.Lease = nil
+	}
+
+	err = recordHistory(ctx, tx, devices.HistoryUpdated, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, devices.ErrUpdateFailed
+	}
+
 	return result, nil
 }
 
-const deleteDevice = `DELETE FROM devices where id = $1`
+const deleteDevice = `UPDATE devices SET deleted_at = $1, version = version + 1 WHERE id = $2`
 
-func (s *service) Delete(ctx context.Context, d devices.Device) (sql.Result, error) {
-	// TODO: Check via database if the device is in use
-	if d.IsDeviceInUse() {
-		return nil, errors.New("cannot delete device while in use state")
+// Delete moves the device to the trash and records it in the device history.
+// Trashed devices are hidden from the Reader methods until restored or
+// purged. The in-use guard is checked against the stored row, not d. Parts
+// of an in-use device and devices that still have parts cannot be deleted.
+func (s *service) Delete(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, d.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.IsDeviceInUse() {
+		return nil, devices.ErrDeviceInUse
+	}
+
+	err = checkParentState(ctx, tx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts bool
+	err = tx.QueryRowContext(ctx, hasParts, cur.Id).Scan(&parts)
+	if err != nil {
+		return nil, err
+	}
+	if parts {
+		return nil, devices.ErrDeviceHasParts
+	}
+	before := *cur
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, deleteDevice, now, cur.Id)
+	if err != nil {
+		return nil, devices.ErrDeleteFailed
+	}
+	cur.DeletedAt = &now
+	cur.Version++
+
+	err = recordHistory(ctx, tx, devices.HistoryDeleted, &before, cur)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := s.db.Exec(deleteDevice, d.Id)
+	err = tx.Commit()
 	if err != nil {
 		return nil, devices.ErrDeleteFailed
 	}
@@ -221,7 +545,8 @@ func (s *service) Delete(ctx context.Context, d devices.Device) (sql.Result, err
 }
 
 // Health checks the health of the database connection by pinging the database.
-// It returns a map with keys indicating various health statistics.
+// It returns a map with keys indicating various health statistics, with the
+// status "down" and the error when the database cannot be reached.
 func (s *service) Health() map[string]string {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -233,7 +558,6 @@ func (s *service) Health() map[string]string {
 	if err != nil {
 		stats["status"] = "down"
 		stats["error"] = fmt.Sprintf("db down: %v", err)
-		log.Fatalf("%s", fmt.Sprintf("db down: %v", err)) // Log the error and terminate the program
 		return stats
 	}
 
@@ -276,6 +600,6 @@ func (s *service) Health() map[string]string {
 // If the connection is successfully closed, it returns nil.
 // If an error occurs while closing the connection, it returns the error.
 func (s *service) Close() error {
-	log.Printf("Disconnected from database: %s", database)
+	log.Printf("Disconnected from database: %s", s.database)
 	return s.db.Close()
 }
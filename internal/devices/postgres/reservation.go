@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"time"
+)
+
+const insertReservation = `INSERT INTO device_reservations (
+  device_id,
+  holder,
+  starts_at,
+  ends_at,
+  created_at
+) VALUES (
+  $1, $2, $3, $4, NOW()
+) RETURNING id, device_id, holder, starts_at, ends_at, created_at`
+
+// Reserve books the device for the requested window. Overlapping windows are
+// rejected by the exclusion constraint on device_reservations, and windows
+// overlapping a scheduled maintenance are refused.
+func (s *service) Reserve(ctx context.Context, deviceId int64, cr devices.CreateReservation) (*devices.Reservation, error) {
+	err := cr.Validate(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the device so a concurrent checkout sees the new reservation.
+	_, err = scanDevice(tx.QueryRowContext(ctx, getDeviceForUpdate, deviceId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMaintenance(ctx, tx, deviceId, cr.StartsAt, cr.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := scanReservation(tx.QueryRowContext(ctx, insertReservation, deviceId, cr.Holder, cr.StartsAt, cr.EndsAt))
+	if pgErrorCode(err) == exclusionViolation {
+		return nil, devices.ErrReservationConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+const getReservations = `SELECT id, device_id, holder, starts_at, ends_at, created_at FROM device_reservations
+WHERE ($1::integer = 0 OR device_id = $1)
+  AND during && tstzrange($2, $3, '[)')
+ORDER BY starts_at, id`
+
+// Reservations lists the reservations overlapping the filter window. Zero
+// times are passed as NULL, which tstzrange treats as unbounded.
+func (s *service) Reservations(ctx context.Context, f devices.ReservationFilter) ([]devices.Reservation, error) {
+	return queryReservations(ctx, s.db, f)
+}
+
+func queryReservations(ctx context.Context, q querier, f devices.ReservationFilter) ([]devices.Reservation, error) {
+	rows, err := q.QueryContext(ctx, getReservations, f.DeviceId, nullTime(f.From), nullTime(f.To))
+	if err != nil {
+		return []devices.Reservation{}, err
+	}
+	defer rows.Close()
+
+	rr := []devices.Reservation{}
+	for rows.Next() {
+		r, err := scanReservation(rows)
+		if err != nil {
+			return []devices.Reservation{}, err
+		}
+		rr = append(rr, *r)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return []devices.Reservation{}, err
+	}
+
+	return rr, nil
+}
+
+func scanReservation(row rowScanner) (*devices.Reservation, error) {
+	var r devices.Reservation
+
+	err := row.Scan(&r.Id, &r.DeviceId, &r.Holder, &r.StartsAt, &r.EndsAt, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+const deleteReservation = `DELETE FROM device_reservations WHERE id = $1 AND device_id = $2`
+
+func (s *service) CancelReservation(ctx context.Context, deviceId int64, reservationId int64) error {
+	result, err := s.db.ExecContext(ctx, deleteReservation, reservationId, deviceId)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return devices.ErrNotExist
+	}
+
+	return nil
+}
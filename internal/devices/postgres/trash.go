@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"time"
+)
+
+const getTrashedDevices = selectDevices + `WHERE d.deleted_at IS NOT NULL
+ORDER BY d.deleted_at DESC`
+
+func (s *service) Trash(ctx context.Context) ([]devices.Device, error) {
+	rows, err := s.db.QueryContext(ctx, getTrashedDevices)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+const getTrashedDeviceForUpdate = selectDevices + `WHERE d.deleted_at IS NOT NULL AND d.id = $1 FOR UPDATE OF d`
+
+const restoreDevice = `UPDATE devices SET deleted_at = NULL, version = version + 1 WHERE id = $1`
+
+// Restore takes a device out of the trash. Devices that are not in the trash
+// yield ErrNotExist.
+func (s *service) Restore(ctx context.Context, id int64) (*devices.Device, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getTrashedDeviceForUpdate, id))
+	if err != nil {
+		return nil, err
+	}
+	before := *cur
+
+	// Another device may have taken its serial number or asset tag while
+	// it was in the trash.
+	_, err = tx.ExecContext(ctx, restoreDevice, cur.Id)
+	if err != nil {
+		return nil, deviceWriteError(err, cur)
+	}
+	cur.DeletedAt = nil
+	cur.Version++
+
+	err = recordHistory(ctx, tx, devices.HistoryRestored, &before, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+const purgeDevices = `DELETE FROM devices WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+
+func (s *service) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, purgeDevices, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,378 @@
+// Package repotest is the conformance suite for devices.Repository. Every
+// backend runs it from its own tests, so they all meet the same contract:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) devices.Repository {
+//			return NewRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// Factory returns an empty repository for a single test. Resources it
+// opens should be released with t.Cleanup.
+type Factory func(t *testing.T) devices.Repository
+
+// Run checks the repository returned by newRepo against the contract every
+// backend must meet.
+func Run(t *testing.T, newRepo Factory) {
+	suite.Run(t, &repositorySuite{newRepo: newRepo})
+}
+
+type repositorySuite struct {
+	suite.Suite
+	newRepo Factory
+	repo    devices.Repository
+	ctx     context.Context
+}
+
+func (s *repositorySuite) SetupTest() {
+	s.repo = s.newRepo(s.T())
+	s.ctx = context.Background()
+}
+
+// create creates a device or fails the test.
+func (s *repositorySuite) create(cd devices.CreateDevice) *devices.Device {
+	d, err := s.repo.Create(s.ctx, cd)
+	s.Require().NoError(err)
+	return d
+}
+
+// update writes d at its current version or fails the test, and returns
+// the stored result.
+func (s *repositorySuite) update(d devices.Device) *devices.Device {
+	_, err := s.repo.Update(s.ctx, d, d.Version)
+	s.Require().NoError(err)
+
+	got, err := s.repo.GetById(s.ctx, d.Id)
+	s.Require().NoError(err)
+	return got
+}
+
+func ids(dd []devices.Device) []int64 {
+	ids := []int64{}
+	for _, d := range dd {
+		ids = append(ids, d.Id)
+	}
+	return ids
+}
+
+func (s *repositorySuite) TestCreateAndGet() {
+	before := time.Now().Add(-time.Second)
+
+	d := s.create(devices.CreateDevice{
+		Name:         "Pixel 8",
+		Brand:        "Google",
+		SerialNumber: " SN-1 ",
+		AssetTag:     "LAB-1",
+		Attributes:   map[string]any{"os": "android"},
+		Labels:       map[string]string{"team": "qa"},
+	})
+
+	s.NotZero(d.Id)
+	s.Equal("Pixel 8", d.Name)
+	s.Equal("Google", d.Brand)
+	s.NotZero(d.BrandId)
+	s.Equal(devices.Available, d.State)
+	s.Equal("SN-1", d.SerialNumber)
+	s.True(d.CreatedAt.After(before), "created_at %s is not set to the creation time", d.CreatedAt)
+	s.NotZero(d.Version)
+
+	got, err := s.repo.GetById(s.ctx, d.Id)
+	s.Require().NoError(err)
+	s.Equal(d.Id, got.Id)
+	s.Equal(d.Name, got.Name)
+	s.Equal(d.Brand, got.Brand)
+	s.Equal(d.Version, got.Version)
+	s.True(d.CreatedAt.Equal(got.CreatedAt))
+	s.Equal(map[string]any{"os": "android"}, got.Attributes)
+	s.Equal(map[string]string{"team": "qa"}, got.Labels)
+
+	got, err = s.repo.GetByAssetTag(s.ctx, "LAB-1")
+	s.Require().NoError(err)
+	s.Equal(d.Id, got.Id)
+
+	other := s.create(devices.CreateDevice{Name: "Pixel 9", Brand: "google"})
+	s.Greater(other.Id, d.Id)
+	s.Equal(d.BrandId, other.BrandId, "brands are matched by name regardless of case")
+}
+
+func (s *repositorySuite) TestCreateInvalid() {
+	_, err := s.repo.Create(s.ctx, devices.CreateDevice{Name: "Pixel 8", Brand: "Google", State: devices.InUse})
+	s.Equal(&devices.ErrInvalidTransition{From: devices.Inactive, To: devices.InUse}, err)
+
+	_, err = s.repo.Create(s.ctx, devices.CreateDevice{Name: "Pixel 8", Brand: "Google", Labels: map[string]string{"-": "x"}})
+	s.ErrorIs(err, devices.ErrInvalidLabel)
+
+	dd, err := s.repo.All(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(dd, "failed creates must not leave devices behind")
+}
+
+func (s *repositorySuite) TestDuplicateFields() {
+	s.create(devices.CreateDevice{Name: "Pixel 8", Brand: "Google", SerialNumber: "SN-1", AssetTag: "LAB-1"})
+
+	_, err := s.repo.Create(s.ctx, devices.CreateDevice{Name: "Pixel 9", Brand: "Google", SerialNumber: "SN-1"})
+	s.ErrorIs(err, devices.ErrDuplicate)
+	s.Equal(&devices.ErrDuplicateField{Field: "serial_number", Value: "SN-1"}, err)
+
+	_, err = s.repo.Create(s.ctx, devices.CreateDevice{Name: "Pixel 9", Brand: "Google", AssetTag: "LAB-1"})
+	s.Equal(&devices.ErrDuplicateField{Field: "asset_tag", Value: "LAB-1"}, err)
+
+	d := s.create(devices.CreateDevice{Name: "Pixel 9", Brand: "Google", SerialNumber: "SN-2"})
+	d.SerialNumber = "SN-1"
+	_, err = s.repo.Update(s.ctx, *d, d.Version)
+	s.Equal(&devices.ErrDuplicateField{Field: "serial_number", Value: "SN-1"}, err)
+
+	// A device in the trash no longer reserves its identifiers.
+	_, err = s.repo.Delete(s.ctx, *d, devices.AnyVersion)
+	s.Require().NoError(err)
+	s.create(devices.CreateDevice{Name: "Pixel 9", Brand: "Google", SerialNumber: "SN-2"})
+}
+
+func (s *repositorySuite) TestNotFound() {
+	_, err := s.repo.GetById(s.ctx, 4242)
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	_, err = s.repo.GetByAssetTag(s.ctx, "LAB-4242")
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	_, err = s.repo.Update(s.ctx, devices.Device{Id: 4242, Name: "Pixel", Brand: "Google"}, devices.AnyVersion)
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	_, err = s.repo.Delete(s.ctx, devices.Device{Id: 4242}, devices.AnyVersion)
+	s.ErrorIs(err, devices.ErrNotExist)
+}
+
+func (s *repositorySuite) TestUpdate() {
+	d := s.create(devices.CreateDevice{Name: "Pixel 8", Brand: "Google", Labels: map[string]string{"team": "qa"}})
+	created := *d
+
+	d.Name = "Pixel 8 Pro"
+	d.BrandId = 0
+	d.Brand = "Alphabet"
+	d.State = devices.Inactive
+	d.Labels = map[string]string{"env": "ci"}
+	got := s.update(*d)
+
+	s.Equal("Pixel 8 Pro", got.Name)
+	s.Equal("Alphabet", got.Brand)
+	s.NotEqual(created.BrandId, got.BrandId)
+	s.Equal(devices.Inactive, got.State)
+	s.Equal(map[string]string{"env": "ci"}, got.Labels)
+	s.Greater(got.Version, created.Version)
+	s.True(created.CreatedAt.Equal(got.CreatedAt))
+
+	// A write at the version read before the update conflicts.
+	_, err := s.repo.Update(s.ctx, created, created.Version)
+	s.Equal(&devices.ErrVersionConflict{Expected: created.Version, Actual: got.Version}, err)
+
+	got.State = devices.InUse
+	_, err = s.repo.Update(s.ctx, *got, devices.AnyVersion)
+	s.Equal(&devices.ErrInvalidTransition{From: devices.Inactive, To: devices.InUse}, err)
+}
+
+func (s *repositorySuite) TestInUseGuards() {
+	d := s.create(devices.CreateDevice{Name: "Pixel 8", Brand: "Google"})
+	d.State = devices.InUse
+	d = s.update(*d)
+
+	_, err := s.repo.Delete(s.ctx, *d, devices.AnyVersion)
+	s.ErrorIs(err, devices.ErrDeviceInUse)
+
+	// The guard is checked against the stored device, not the argument.
+	stale := *d
+	stale.State = devices.Available
+	_, err = s.repo.Delete(s.ctx, stale, devices.AnyVersion)
+	s.ErrorIs(err, devices.ErrDeviceInUse)
+
+	renamed := *d
+	renamed.Name = "Renamed"
+	_, err = s.repo.Update(s.ctx, renamed, d.Version)
+	s.ErrorIs(err, devices.ErrDeviceInUseChange, "an in-use device cannot be renamed")
+
+	rebranded := *d
+	rebranded.BrandId = 0
+	rebranded.Brand = "Apple"
+	_, err = s.repo.Update(s.ctx, rebranded, d.Version)
+	s.ErrorIs(err, devices.ErrDeviceInUseChange, "the brand of an in-use device cannot change")
+
+	got, err := s.repo.GetById(s.ctx, d.Id)
+	s.Require().NoError(err)
+	s.Equal("Pixel 8", got.Name)
+	s.Equal(d.Version, got.Version, "refused writes must not change the device")
+
+	got.State = devices.Available
+	got = s.update(*got)
+	_, err = s.repo.Delete(s.ctx, *got, got.Version)
+	s.NoError(err)
+}
+
+func (s *repositorySuite) TestDelete() {
+	d := s.create(devices.CreateDevice{Name: "Pixel 8", Brand: "Google", AssetTag: "LAB-1"})
+	kept := s.create(devices.CreateDevice{Name: "Pixel 9", Brand: "Google"})
+
+	_, err := s.repo.Delete(s.ctx, *d, d.Version+1)
+	s.Equal(&devices.ErrVersionConflict{Expected: d.Version + 1, Actual: d.Version}, err)
+
+	_, err = s.repo.Delete(s.ctx, *d, d.Version)
+	s.Require().NoError(err)
+
+	_, err = s.repo.GetById(s.ctx, d.Id)
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	_, err = s.repo.GetByAssetTag(s.ctx, "LAB-1")
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	_, err = s.repo.Delete(s.ctx, *d, devices.AnyVersion)
+	s.ErrorIs(err, devices.ErrNotExist)
+
+	for name, list := range map[string]func() ([]devices.Device, error){
+		"All":        func() ([]devices.Device, error) { return s.repo.All(s.ctx) },
+		"GetByBrand": func() ([]devices.Device, error) { return s.repo.GetByBrand(s.ctx, "Google") },
+		"GetByState": func() ([]devices.Device, error) { return s.repo.GetByState(s.ctx, devices.Available) },
+	} {
+		dd, err := list()
+		s.Require().NoError(err, name)
+		s.Equal([]int64{kept.Id}, ids(dd), name)
+	}
+}
+
+func (s *repositorySuite) TestGetByBrand() {
+	apple := s.create(devices.CreateDevice{Name: "iPhone", Brand: "Apple"})
+	s.create(devices.CreateDevice{Name: "Pixel", Brand: "Google"})
+	ipad := s.create(devices.CreateDevice{Name: "iPad", Brand: "apple"})
+
+	for _, b := range []string{"Apple", "apple", " APPLE ", strconv.FormatInt(apple.BrandId, 10)} {
+		dd, err := s.repo.GetByBrand(s.ctx, b)
+		s.Require().NoError(err, b)
+		s.Equal([]int64{apple.Id, ipad.Id}, ids(dd), b)
+	}
+
+	dd, err := s.repo.GetByBrand(s.ctx, "Nokia")
+	s.NoError(err, "an unknown brand has no devices")
+	s.Empty(dd)
+}
+
+func (s *repositorySuite) TestGetByState() {
+	a := s.create(devices.CreateDevice{Name: "a", Brand: "Google"})
+	b := s.create(devices.CreateDevice{Name: "b", Brand: "Google", State: devices.Inactive})
+	c := s.create(devices.CreateDevice{Name: "c", Brand: "Google"})
+	c.State = devices.InUse
+	s.update(*c)
+
+	for state, want := range map[devices.DeviceState][]int64{
+		devices.Available:   {a.Id},
+		devices.Inactive:    {b.Id},
+		devices.InUse:       {c.Id},
+		devices.Maintenance: {},
+	} {
+		dd, err := s.repo.GetByState(s.ctx, state)
+		s.Require().NoError(err, state)
+		s.Equal(want, ids(dd), state)
+	}
+}
+
+func (s *repositorySuite) TestOrdering() {
+	var want []int64
+	for i := 0; i < 5; i++ {
+		d := s.create(devices.CreateDevice{Name: "Device " + strconv.Itoa(i), Brand: "Google"})
+		want = append(want, d.Id)
+	}
+
+	// Updating a device must not move it in the listings.
+	first, err := s.repo.GetById(s.ctx, want[0])
+	s.Require().NoError(err)
+	first.Name = "Renamed"
+	s.update(*first)
+
+	all, err := s.repo.All(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(want, ids(all), "All is ordered by id")
+
+	byBrand, err := s.repo.GetByBrand(s.ctx, "Google")
+	s.Require().NoError(err)
+	s.Equal(want, ids(byBrand), "GetByBrand is ordered by id")
+
+	byState, err := s.repo.GetByState(s.ctx, devices.Available)
+	s.Require().NoError(err)
+	s.Equal(want, ids(byState), "GetByState is ordered by id")
+}
+
+func (s *repositorySuite) TestConcurrentCreates() {
+	const n = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.repo.Create(s.ctx, devices.CreateDevice{
+				Name:         "Device " + strconv.Itoa(i),
+				Brand:        "Google",
+				SerialNumber: "SN-" + strconv.Itoa(i),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		s.NoError(err)
+	}
+
+	dd, err := s.repo.All(s.ctx)
+	s.Require().NoError(err)
+	s.Len(dd, n)
+
+	seen := map[int64]bool{}
+	for _, d := range dd {
+		s.False(seen[d.Id], "id %d was handed out twice", d.Id)
+		seen[d.Id] = true
+	}
+}
+
+func (s *repositorySuite) TestConcurrentUpdates() {
+	const n = 10
+
+	d := s.create(devices.CreateDevice{Name: "Pixel 8", Brand: "Google"})
+
+	// Every writer read the same version, so exactly one of them wins.
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := *d
+			u.Name = "Writer " + strconv.Itoa(i)
+			_, errs[i] = s.repo.Update(s.ctx, u, d.Version)
+		}(i)
+	}
+	wg.Wait()
+
+	won := 0
+	for _, err := range errs {
+		var conflict *devices.ErrVersionConflict
+		switch {
+		case err == nil:
+			won++
+		case s.ErrorAs(err, &conflict):
+		}
+	}
+	s.Equal(1, won)
+
+	got, err := s.repo.GetById(s.ctx, d.Id)
+	s.Require().NoError(err)
+	s.Greater(got.Version, d.Version)
+}
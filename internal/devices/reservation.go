@@ -0,0 +1,62 @@
+package devices
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrReservationConflict = errors.New("reservation overlaps an existing reservation")
+	ErrInvalidReservation  = errors.New("reservation needs a holder and a window that ends in the future after it starts")
+	ErrDeviceReserved      = errors.New("device is reserved by someone else")
+)
+
+// Reservation books a device for a future window [StartsAt, EndsAt).
+type Reservation struct {
+	Id        int64     `json:"id"`
+	DeviceId  int64     `json:"device_id"`
+	Holder    string    `json:"holder"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateReservation represents the model to reserve a device.
+type CreateReservation struct {
+	Holder   string    `json:"holder"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// ReservationFilter restricts a reservation query to windows overlapping
+// [From, To). A zero DeviceId matches every device and a zero time leaves
+// that side of the range open.
+type ReservationFilter struct {
+	DeviceId int64
+	From     time.Time
+	To       time.Time
+}
+
+// Validate checks the requested window against now.
+func (cr CreateReservation) Validate(now time.Time) error {
+	if cr.Holder == "" || !cr.StartsAt.Before(cr.EndsAt) || !cr.EndsAt.After(now) {
+		return ErrInvalidReservation
+	}
+	return nil
+}
+
+// Overlaps reports whether the reservation intersects [start, end).
+func (r *Reservation) Overlaps(start, end time.Time) bool {
+	return r.StartsAt.Before(end) && start.Before(r.EndsAt)
+}
+
+// CheckReserved returns ErrDeviceReserved if any of rr held by someone other
+// than holder overlaps [start, end).
+func CheckReserved(rr []Reservation, holder string, start, end time.Time) error {
+	for i := range rr {
+		if rr[i].Holder != holder && rr[i].Overlaps(start, end) {
+			return ErrDeviceReserved
+		}
+	}
+	return nil
+}
@@ -1,15 +1,249 @@
 package devices
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
 
+// Service is the application layer between the HTTP handlers and a
+// Repository. Writes load the current device, apply the domain rules to it
+// and persist the result at the version the rules were checked against, so
+// a concurrent change fails with *ErrVersionConflict instead of slipping
+// past them. The repository still checks the rules again under its own
+// locks.
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Create validates cd and creates the device. New devices start Inactive,
+// so the requested state must be reachable from there.
+func (s *Service) Create(ctx context.Context, cd CreateDevice) (*Device, error) {
+	err := cd.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = NewDevice(cd.Name, cd.Brand).ChangeDeviceState(cd.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, cd)
+}
+
+// maxWriteAttempts bounds how often Update and Delete retry a write made
+// without an expected version that lost a race with another write.
+const maxWriteAttempts = 3
+
+// Update applies the writable fields of d to the stored device d.Id if it
+// is at the expected version and returns the updated device. With
+// AnyVersion a concurrent change is retried against the new version, and
+// ErrConcurrentUpdate is returned if it keeps losing.
+func (s *Service) Update(ctx context.Context, d Device, version int64) (*Device, error) {
+	err := d.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.retry(version, func() error {
+		return s.update(ctx, d, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetById(ctx, d.Id)
+}
+
+func (s *Service) update(ctx context.Context, d Device, version int64) error {
+	cur, err := s.load(ctx, d.Id, version)
+	if err != nil {
+		return err
+	}
+
+	if d.State != cur.State {
+		err = s.checkParentState(ctx, cur)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cur.Apply(d)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.Update(ctx, *cur, cur.Version)
+	return err
+}
+
+// Delete moves the device id to the trash if it is at the expected version.
+// Devices that are in use, or part of a device that is, are refused. Like
+// Update, a delete with AnyVersion is retried after a concurrent change.
+func (s *Service) Delete(ctx context.Context, id int64, version int64) error {
+	return s.retry(version, func() error {
+		return s.delete(ctx, id, version)
+	})
+}
+
+func (s *Service) delete(ctx context.Context, id int64, version int64) error {
+	cur, err := s.load(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	if cur.IsDeviceInUse() {
+		return ErrDeviceInUse
+	}
+
+	err = s.checkParentState(ctx, cur)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.Delete(ctx, *cur, cur.Version)
+	return err
+}
+
+// retry runs write, which loads the device and writes it back at the loaded
+// version. A caller that sent an expected version gets its
+// *ErrVersionConflict as is; for AnyVersion the conflict only means another
+// write landed in between, so write runs again on the fresh device.
+func (s *Service) retry(version int64, write func() error) error {
+	var conflict *ErrVersionConflict
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if version != AnyVersion || !errors.As(err, &conflict) {
+			return err
+		}
+		if attempt == maxWriteAttempts {
+			return fmt.Errorf("%w: %v", ErrConcurrentUpdate, err)
+		}
+	}
+}
+
+// load returns the stored device id if it is at the expected version.
+func (s *Service) load(ctx context.Context, id int64, version int64) (*Device, error) {
+	cur, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return cur, nil
+}
+
+func (s *Service) checkParentState(ctx context.Context, d *Device) error {
+	if d.ParentId == 0 {
+		return nil
+	}
+
+	p, err := s.repo.GetById(ctx, d.ParentId)
+	if err != nil {
+		return err
+	}
+	return d.CheckParentState(p)
+}
+
+func (s *Service) GetById(ctx context.Context, id int64) (*Device, error) {
+	return s.repo.GetById(ctx, id)
+}
+
+func (s *Service) GetByAssetTag(ctx context.Context, tag string) (*Device, error) {
+	return s.repo.GetByAssetTag(ctx, tag)
+}
+
+func (s *Service) GetByBrand(ctx context.Context, b string) ([]Device, error) {
+	return s.repo.GetByBrand(ctx, b)
+}
+
+func (s *Service) GetByState(ctx context.Context, ds DeviceState) ([]Device, error) {
+	if !ds.IsValid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidState, ds)
+	}
+	return s.repo.GetByState(ctx, ds)
+}
+
+func (s *Service) All(ctx context.Context) ([]Device, error) {
+	return s.repo.All(ctx)
+}
+
+func (s *Service) Health() map[string]string {
+	return s.repo.Health()
+}
+
+func (s *Service) Close() error {
+	return s.repo.Close()
+}
+
+// Apply copies the writable fields of u onto the device. The name and
+// brand of an in-use device are fixed and the state follows the state
+// machine. Location, ownership, parent and lease change through their own
+// operations and are left as they are.
+func (d *Device) Apply(u Device) error {
+	if u.Name != d.Name {
+		err := d.ChangeDeviceName(u.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case u.BrandId == 0 && u.Brand == "":
+		// The brand follows the model; the repository checks it once
+		// resolved.
+		d.Brand, d.BrandId = "", 0
+	case u.BrandId != 0 && u.BrandId != d.BrandId,
+		u.BrandId == 0 && !strings.EqualFold(u.Brand, d.Brand):
+		err := d.ChangeDeviceBrand(u.Brand)
+		if err != nil {
+			return err
+		}
+		d.BrandId = u.BrandId
+	}
+	d.ModelId = u.ModelId
+	d.SerialNumber = strings.TrimSpace(u.SerialNumber)
+	d.AssetTag = strings.TrimSpace(u.AssetTag)
+
+	err := d.ChangeDeviceState(u.State)
+	if err != nil {
+		return err
+	}
+
+	d.Lifecycle = u.Lifecycle
+	d.Attributes = u.Attributes
+	d.Labels = u.Labels
+	return nil
+}
+
+// ChangeDeviceState moves the device to ds if the state machine allows it,
+// otherwise it returns an *ErrInvalidTransition and leaves the device as is.
 func (d *Device) ChangeDeviceState(ds DeviceState) error {
+	if !d.State.CanTransitionTo(ds) {
+		return &ErrInvalidTransition{From: d.State, To: ds}
+	}
+
 	d.State = ds
 	return nil
 }
 
+// AllowedTransitions returns the states the device can move to next.
+func (d *Device) AllowedTransitions() []DeviceState {
+	return d.State.NextStates()
+}
+
 func (d *Device) ChangeDeviceName(n string) error {
 	if d.State == InUse {
-		return fmt.Errorf("trying to change device %d name while in use", d.Id)
+		return fmt.Errorf("trying to change device %d name %w%s", d.Id, ErrDeviceInUseChange, d.heldBy())
 	}
 
 	d.Name = n
@@ -18,13 +252,21 @@ func (d *Device) ChangeDeviceName(n string) error {
 
 func (d *Device) ChangeDeviceBrand(b string) error {
 	if d.State == InUse {
-		return fmt.Errorf("trying to change device %d brand while in use", d.Id)
+		return fmt.Errorf("trying to change device %d brand %w%s", d.Id, ErrDeviceInUseChange, d.heldBy())
 	}
 
 	d.Brand = b
 	return nil
 }
 
+// heldBy describes the holder of the active lease for in-use errors.
+func (d *Device) heldBy() string {
+	if d.Lease == nil {
+		return ""
+	}
+	return fmt.Sprintf(" by %s until %s", d.Lease.Holder, d.Lease.ExpectedReturnAt.Format(dateTimeApiLayout))
+}
+
 func (d *Device) CreationTimeFormatted() string {
 	return d.CreatedAt.Format(dateTimeApiLayout)
 }
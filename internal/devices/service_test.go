@@ -0,0 +1,153 @@
+package devices_test
+
+import (
+	"context"
+	"testing"
+
+	"devices_api/internal/devices"
+	"devices_api/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestServiceCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	s := devices.NewService(repo)
+	ctx := context.Background()
+
+	cd := devices.CreateDevice{Name: "Pixel 8", Brand: "Google"}
+	repo.EXPECT().Create(ctx, cd).Return(&devices.Device{Id: 1, Name: "Pixel 8"}, nil)
+
+	d, err := s.Create(ctx, cd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), d.Id)
+
+	// New devices start Inactive and cannot be created in use.
+	_, err = s.Create(ctx, devices.CreateDevice{Name: "Pixel 8", Brand: "Google", State: devices.InUse})
+	assert.Equal(t, &devices.ErrInvalidTransition{From: devices.Inactive, To: devices.InUse}, err)
+
+	var verr *devices.ErrValidation
+	_, err = s.Create(ctx, devices.CreateDevice{})
+	assert.ErrorAs(t, err, &verr)
+}
+
+func TestServiceUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	s := devices.NewService(repo)
+	ctx := context.Background()
+
+	cur := devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", BrandId: 2, State: devices.Available, Version: 5, LocationId: 9}
+	want := cur
+	want.Name = "Pixel 8 Pro"
+	want.State = devices.InUse
+	want.Labels = map[string]string{"team": "qa"}
+
+	gomock.InOrder(
+		repo.EXPECT().GetById(ctx, int64(1)).Return(&cur, nil),
+		repo.EXPECT().Update(ctx, want, int64(5)).Return(nil, nil),
+		repo.EXPECT().GetById(ctx, int64(1)).Return(&want, nil),
+	)
+
+	// Fields the update does not own, such as the location, are kept.
+	d, err := s.Update(ctx, devices.Device{Id: 1, Name: "Pixel 8 Pro", Brand: "google", State: devices.InUse, Labels: map[string]string{"team": "qa"}}, devices.AnyVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, &want, d)
+}
+
+func TestServiceUpdate_Rules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	s := devices.NewService(repo)
+	ctx := context.Background()
+
+	inUse := devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", BrandId: 2, State: devices.InUse, Version: 5}
+	repo.EXPECT().GetById(ctx, int64(1)).Return(&inUse, nil).AnyTimes()
+
+	_, err := s.Update(ctx, devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", State: devices.InUse}, 4)
+	assert.Equal(t, &devices.ErrVersionConflict{Expected: 4, Actual: 5}, err)
+
+	_, err = s.Update(ctx, devices.Device{Id: 1, Name: "Renamed", Brand: "Google", State: devices.InUse}, 5)
+	assert.ErrorIs(t, err, devices.ErrDeviceInUseChange)
+
+	_, err = s.Update(ctx, devices.Device{Id: 1, Name: "Pixel 8", Brand: "Apple", State: devices.InUse}, 5)
+	assert.ErrorIs(t, err, devices.ErrDeviceInUseChange)
+
+	_, err = s.Update(ctx, devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", State: devices.Inactive}, 5)
+	assert.Equal(t, &devices.ErrInvalidTransition{From: devices.InUse, To: devices.Inactive}, err)
+
+	// Parts of an in-use device keep their state.
+	part := devices.Device{Id: 3, Name: "SIM", Brand: "Google", BrandId: 2, State: devices.Available, ParentId: 1}
+	repo.EXPECT().GetById(ctx, int64(3)).Return(&part, nil)
+
+	_, err = s.Update(ctx, devices.Device{Id: 3, Name: "SIM", Brand: "Google", State: devices.Inactive}, devices.AnyVersion)
+	assert.ErrorIs(t, err, devices.ErrParentInUse)
+}
+
+func TestServiceUpdate_Retry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	s := devices.NewService(repo)
+	ctx := context.Background()
+
+	v5 := devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", BrandId: 2, State: devices.Available, Version: 5}
+	v6 := v5
+	v6.Version = 6
+	u := devices.Device{Id: 1, Name: "Pixel 8", Brand: "Google", State: devices.Inactive}
+
+	// Without an expected version, a write that lost a race is retried on
+	// the fresh device.
+	want := v6
+	want.State = devices.Inactive
+	stale := v5
+	stale.State = devices.Inactive
+	gomock.InOrder(
+		repo.EXPECT().GetById(ctx, int64(1)).Return(&v5, nil),
+		repo.EXPECT().Update(ctx, stale, int64(5)).Return(nil, &devices.ErrVersionConflict{Expected: 5, Actual: 6}),
+		repo.EXPECT().GetById(ctx, int64(1)).Return(&v6, nil),
+		repo.EXPECT().Update(ctx, want, int64(6)).Return(nil, nil),
+		repo.EXPECT().GetById(ctx, int64(1)).Return(&want, nil),
+	)
+	d, err := s.Update(ctx, u, devices.AnyVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, &want, d)
+
+	// A caller that sent a version gets the conflict.
+	repo.EXPECT().GetById(ctx, int64(1)).Return(&v5, nil)
+	repo.EXPECT().Update(ctx, stale, int64(5)).Return(nil, &devices.ErrVersionConflict{Expected: 5, Actual: 6})
+	_, err = s.Update(ctx, u, 5)
+	assert.Equal(t, &devices.ErrVersionConflict{Expected: 5, Actual: 6}, err)
+
+	// Retries are bounded.
+	repo.EXPECT().GetById(ctx, int64(1)).Return(&v5, nil).Times(3)
+	repo.EXPECT().Update(ctx, stale, int64(5)).Return(nil, &devices.ErrVersionConflict{Expected: 5, Actual: 6}).Times(3)
+	_, err = s.Update(ctx, u, devices.AnyVersion)
+	assert.ErrorIs(t, err, devices.ErrConcurrentUpdate)
+
+	repo.EXPECT().GetById(ctx, int64(1)).Return(&v5, nil).Times(3)
+	repo.EXPECT().Delete(ctx, v5, int64(5)).Return(nil, &devices.ErrVersionConflict{Expected: 5, Actual: 6}).Times(3)
+	assert.ErrorIs(t, s.Delete(ctx, 1, devices.AnyVersion), devices.ErrConcurrentUpdate)
+}
+
+func TestServiceDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockRepository(ctrl)
+	s := devices.NewService(repo)
+	ctx := context.Background()
+
+	inUse := devices.Device{Id: 1, State: devices.InUse, Version: 2}
+	available := devices.Device{Id: 2, State: devices.Available, Version: 7}
+	repo.EXPECT().GetById(ctx, int64(1)).Return(&inUse, nil).AnyTimes()
+	repo.EXPECT().GetById(ctx, int64(2)).Return(&available, nil).AnyTimes()
+	repo.EXPECT().GetById(ctx, int64(3)).Return(nil, devices.ErrNotExist)
+
+	// The in-use guard is checked against the stored device.
+	assert.ErrorIs(t, s.Delete(ctx, 1, devices.AnyVersion), devices.ErrDeviceInUse)
+	assert.ErrorIs(t, s.Delete(ctx, 3, devices.AnyVersion), devices.ErrNotExist)
+	assert.Equal(t, &devices.ErrVersionConflict{Expected: 6, Actual: 7}, s.Delete(ctx, 2, 6))
+
+	repo.EXPECT().Delete(ctx, available, int64(7)).Return(nil, nil)
+	assert.NoError(t, s.Delete(ctx, 2, 7))
+}
@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"devices_api/internal/devices"
+	"errors"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// uniqueDeviceFields maps the unique columns of the devices table, as named
+// in SQLite constraint errors, to their JSON name.
+var uniqueDeviceFields = map[string]string{
+	"devices.serial_number": "serial_number",
+	"devices.asset_tag":     "asset_tag",
+}
+
+// deviceWriteError maps a unique violation on d to an
+// *devices.ErrDuplicateField naming the conflicting field.
+func deviceWriteError(err error, d *devices.Device) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+
+	// The message reads "UNIQUE constraint failed: devices.serial_number".
+	_, column, _ := strings.Cut(sqliteErr.Error(), ": ")
+	switch uniqueDeviceFields[column] {
+	case "serial_number":
+		return &devices.ErrDuplicateField{Field: "serial_number", Value: d.SerialNumber}
+	case "asset_tag":
+		return &devices.ErrDuplicateField{Field: "asset_tag", Value: d.AssetTag}
+	}
+	return devices.ErrDuplicate
+}
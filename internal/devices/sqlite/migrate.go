@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrations holds the schema as numbered SQL files, such as
+// 0001_devices.sql, applied in order.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+const createMigrations = `CREATE TABLE IF NOT EXISTS migrations (
+	sequence           INTEGER PRIMARY KEY,
+	filename           TEXT NOT NULL,
+	revision           TEXT NOT NULL,
+	revision_timestamp TIMESTAMP NOT NULL
+)`
+
+const getLastMigration = `SELECT COALESCE(MAX(sequence), 0) FROM migrations`
+
+const insertMigration = `INSERT INTO migrations (sequence, filename, revision, revision_timestamp) VALUES ($1, $2, $3, $4)`
+
+// migrate applies the migrations newer than the last one recorded in the
+// migrations table, each in its own transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, createMigrations)
+	if err != nil {
+		return err
+	}
+
+	var last int
+	err = db.QueryRowContext(ctx, getLastMigration).Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		name := strings.TrimPrefix(f, "migrations/")
+		seq, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("migration %s: want a numbered file name", name)
+		}
+		if seq <= last {
+			continue
+		}
+
+		err = applyMigration(ctx, db, seq, name)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, seq int, name string) error {
+	body, err := migrations.ReadFile("migrations/" + name)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, string(body))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertMigration, seq, name, hex.EncodeToString(sum[:]), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,53 @@
+CREATE TABLE IF NOT EXISTS brands(
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    name              TEXT NOT NULL,
+    slug              TEXT NOT NULL UNIQUE,
+    created_at        TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS devices(
+    id                INTEGER PRIMARY KEY AUTOINCREMENT,
+    d_name            TEXT NOT NULL,
+    brand_id          INTEGER NOT NULL REFERENCES brands (id),
+    model_id          INTEGER,
+    serial_number     TEXT,
+    asset_tag         TEXT,
+    location_id       INTEGER,
+    owner_id          INTEGER,
+    assignee_id       INTEGER,
+    parent_id         INTEGER REFERENCES devices (id) ON DELETE SET NULL CHECK (parent_id <> id),
+    purchase_date     DATE,
+    warranty_expires_on DATE CHECK (warranty_expires_on >= purchase_date),
+    end_of_life_on    DATE CHECK (end_of_life_on > purchase_date),
+    d_state           INTEGER NOT NULL,
+    created_at        TIMESTAMP NOT NULL,
+    version           INTEGER NOT NULL DEFAULT 1,
+    deleted_at        TIMESTAMP,
+    attributes        TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS devices_brand_id_idx
+    ON devices (brand_id);
+
+CREATE INDEX IF NOT EXISTS devices_d_state_idx
+    ON devices (d_state);
+
+-- Devices in the trash do not reserve their identifiers.
+CREATE UNIQUE INDEX IF NOT EXISTS devices_serial_number_key
+    ON devices (serial_number) WHERE deleted_at IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS devices_asset_tag_key
+    ON devices (asset_tag) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS devices_parent_id_idx
+    ON devices (parent_id) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS device_labels(
+    device_id         INTEGER NOT NULL REFERENCES devices (id) ON DELETE CASCADE,
+    key               TEXT NOT NULL,
+    value             TEXT NOT NULL,
+    PRIMARY KEY (device_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS device_labels_key_value_idx
+    ON device_labels (key, value);
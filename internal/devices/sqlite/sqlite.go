@@ -0,0 +1,572 @@
+// Package sqlite provides a devices.Repository stored in a single SQLite
+// file, for installs without a Postgres server. It matches the postgres
+// backend for the Reader and Writer methods; the catalog, leases and the
+// other optional capabilities are not supported.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"devices_api/internal/devices"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type service struct {
+	db *sql.DB
+}
+
+// New opens the SQLite database at path, creating it if needed, and brings
+// its schema up to date. SQLite allows one writer at a time, so the
+// repository uses a single connection and transactions never wait on each
+// other inside the process.
+func New(ctx context.Context, path string) (devices.Repository, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_foreign_keys": {"on"},
+		"_busy_timeout": {"5000"},
+		"_journal_mode": {"WAL"},
+	}.Encode()
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	err = migrate(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &service{db: db}, nil
+}
+
+const selectDevices = `SELECT d.id, d.d_name, b.name, d.d_state, d.created_at, d.brand_id, d.model_id, d.serial_number, d.asset_tag, d.location_id, d.owner_id, d.assignee_id, d.parent_id,
+  d.purchase_date, d.warranty_expires_on, d.end_of_life_on, d.version, d.deleted_at, d.attributes,
+  (SELECT json_group_object(dl.key, dl.value) FROM device_labels dl WHERE dl.device_id = d.id)
+FROM devices d
+JOIN brands b ON b.id = d.brand_id
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanDevice(row rowScanner) (*devices.Device, error) {
+	var (
+		d          devices.Device
+		modelId    sql.NullInt64
+		serial     sql.NullString
+		tag        sql.NullString
+		locationId sql.NullInt64
+		ownerId    sql.NullInt64
+		assigneeId sql.NullInt64
+		parentId   sql.NullInt64
+		attrs      []byte
+		labels     []byte
+	)
+
+	err := row.Scan(
+		&d.Id,
+		&d.Name,
+		&d.Brand,
+		&d.State,
+		&d.CreatedAt,
+		&d.BrandId,
+		&modelId,
+		&serial,
+		&tag,
+		&locationId,
+		&ownerId,
+		&assigneeId,
+		&parentId,
+		&d.PurchaseDate,
+		&d.WarrantyExpiresOn,
+		&d.EndOfLifeOn,
+		&d.Version,
+		&d.DeletedAt,
+		&attrs,
+		&labels,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &devices.Device{}, devices.ErrNotExist
+	}
+	if err != nil {
+		return &devices.Device{}, err
+	}
+	d.ModelId = modelId.Int64
+	d.SerialNumber = serial.String
+	d.AssetTag = tag.String
+	d.LocationId = locationId.Int64
+	d.OwnerId = ownerId.Int64
+	d.AssigneeId = assigneeId.Int64
+	d.ParentId = parentId.Int64
+
+	err = unmarshalMap(attrs, &d.Attributes)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = unmarshalMap(labels, &d.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	return &d, nil
+}
+
+func scanDevices(rows *sql.Rows) ([]devices.Device, error) {
+	dd := []devices.Device{}
+
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			return []devices.Device{}, err
+		}
+		dd = append(dd, *d)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	return dd, nil
+}
+
+func queryDevices(ctx context.Context, q querier, query string, args ...any) ([]devices.Device, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return []devices.Device{}, err
+	}
+	defer rows.Close()
+
+	return scanDevices(rows)
+}
+
+// unmarshalMap decodes a JSON object, leaving m nil when it is empty.
+func unmarshalMap[V any](b []byte, m *map[string]V) error {
+	*m = nil
+	if len(b) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(b, m)
+	if err != nil {
+		return err
+	}
+	if len(*m) == 0 {
+		*m = nil
+	}
+	return nil
+}
+
+func marshalAttributes(attrs map[string]any) ([]byte, error) {
+	if attrs == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attrs)
+}
+
+func nullId(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+// nullString maps an empty optional value to NULL, so that unique
+// constraints only apply to devices that have the value.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+const getBrandById = `SELECT id, name FROM brands WHERE id = ?`
+
+const getBrandBySlug = `SELECT id, name FROM brands WHERE slug = ?`
+
+const insertBrand = `INSERT INTO brands (name, slug, created_at) VALUES (?, ?, ?)`
+
+// resolveBrand looks a brand up by id, or by the slug of its name, adding
+// unknown names to the brands table. Models are not kept, so any modelId
+// is unknown.
+func resolveBrand(ctx context.Context, tx *sql.Tx, id int64, name string, modelId int64) (*devices.Brand, error) {
+	if modelId != 0 {
+		return nil, devices.ErrUnknownModel
+	}
+
+	var b devices.Brand
+	if id != 0 {
+		err := tx.QueryRowContext(ctx, getBrandById, id).Scan(&b.Id, &b.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, devices.ErrUnknownBrand
+		}
+		return &b, err
+	}
+
+	slug := devices.BrandSlug(name)
+	if slug == "" {
+		return nil, devices.ErrUnknownBrand
+	}
+
+	err := tx.QueryRowContext(ctx, getBrandBySlug, slug).Scan(&b.Id, &b.Name)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return &b, err
+	}
+
+	b.Name = strings.TrimSpace(name)
+	res, err := tx.ExecContext(ctx, insertBrand, b.Name, slug, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	b.Id, err = res.LastInsertId()
+	return &b, err
+}
+
+const upsertLabel = `INSERT INTO device_labels (device_id, key, value) VALUES (?, ?, ?)
+ON CONFLICT (device_id, key) DO UPDATE SET value = excluded.value`
+
+const deleteAllLabels = `DELETE FROM device_labels WHERE device_id = ?`
+
+func setLabels(ctx context.Context, tx *sql.Tx, deviceId int64, labels map[string]string) error {
+	for k, v := range labels {
+		_, err := tx.ExecContext(ctx, upsertLabel, deviceId, k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkParentState refuses changes to d while the assembly it is part of
+// is in use. A parent in the trash no longer holds its parts.
+func checkParentState(ctx context.Context, q querier, d *devices.Device) error {
+	if d.ParentId == 0 {
+		return nil
+	}
+
+	p, err := scanDevice(q.QueryRowContext(ctx, getDeviceById, d.ParentId))
+	if errors.Is(err, devices.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return d.CheckParentState(p)
+}
+
+const createDevice = `INSERT INTO devices (
+	d_name, brand_id, d_state, attributes, model_id, serial_number, asset_tag,
+	location_id, owner_id, assignee_id, purchase_date, warranty_expires_on, end_of_life_on, created_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (s *service) Create(ctx context.Context, cd devices.CreateDevice) (*devices.Device, error) {
+	nd := devices.NewDevice(cd.Name, cd.Brand)
+	nd.SerialNumber = strings.TrimSpace(cd.SerialNumber)
+	nd.AssetTag = strings.TrimSpace(cd.AssetTag)
+	err := nd.ChangeDeviceState(cd.State)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = devices.ValidateLabels(cd.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = cd.ValidateDates()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	attrs, err := marshalAttributes(cd.Attributes)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+	defer tx.Rollback()
+
+	b, err := resolveBrand(ctx, tx, cd.BrandId, cd.Brand, cd.ModelId)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, createDevice,
+		nd.Name, b.Id, nd.State, attrs, nullId(cd.ModelId), nullString(nd.SerialNumber), nullString(nd.AssetTag),
+		nullId(cd.LocationId), nullId(cd.OwnerId), nullId(cd.AssigneeId),
+		cd.PurchaseDate, cd.WarrantyExpiresOn, cd.EndOfLifeOn, time.Now().UTC(),
+	)
+	if err != nil {
+		return &devices.Device{}, deviceWriteError(err, nd)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = setLabels(ctx, tx, id, cd.Labels)
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	d, err := scanDevice(tx.QueryRowContext(ctx, getDeviceById, id))
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return &devices.Device{}, err
+	}
+
+	return d, nil
+}
+
+const getDeviceById = selectDevices + `WHERE d.deleted_at IS NULL AND d.id = ?`
+
+func (s *service) GetById(ctx context.Context, id int64) (*devices.Device, error) {
+	return scanDevice(s.db.QueryRowContext(ctx, getDeviceById, id))
+}
+
+const getDevicesByBrand = selectDevices + `WHERE d.deleted_at IS NULL AND (CAST(b.id AS TEXT) = ? OR b.slug = ?) ORDER BY d.id`
+
+// GetByBrand lists the devices of a brand given by id, name or slug.
+func (s *service) GetByBrand(ctx context.Context, brand string) ([]devices.Device, error) {
+	return queryDevices(ctx, s.db, getDevicesByBrand, strings.TrimSpace(brand), devices.BrandSlug(brand))
+}
+
+const getDeviceByAssetTag = selectDevices + `WHERE d.deleted_at IS NULL AND d.asset_tag = ?`
+
+// GetByAssetTag looks a device up by the asset tag printed on its label.
+func (s *service) GetByAssetTag(ctx context.Context, tag string) (*devices.Device, error) {
+	return scanDevice(s.db.QueryRowContext(ctx, getDeviceByAssetTag, strings.TrimSpace(tag)))
+}
+
+const getDevicesByState = selectDevices + `WHERE d.deleted_at IS NULL AND d.d_state = ? ORDER BY d.id`
+
+func (s *service) GetByState(ctx context.Context, state devices.DeviceState) ([]devices.Device, error) {
+	return queryDevices(ctx, s.db, getDevicesByState, int(state))
+}
+
+const getAllDevices = selectDevices + `WHERE d.deleted_at IS NULL ORDER BY d.id`
+
+func (s *service) All(ctx context.Context) ([]devices.Device, error) {
+	return queryDevices(ctx, s.db, getAllDevices)
+}
+
+// Find lists the devices matching f. The filter is applied in Go, which is
+// fine for the device counts of a single lab.
+func (s *service) Find(ctx context.Context, f devices.DeviceFilter) ([]devices.Device, error) {
+	dd, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []devices.Device{}
+	for i := range dd {
+		if f.Matches(&dd[i]) {
+			found = append(found, dd[i])
+		}
+	}
+	return found, nil
+}
+
+const updateDevice = `UPDATE devices SET
+	d_name = ?, brand_id = ?, d_state = ?, attributes = ?, model_id = ?,
+	serial_number = ?, asset_tag = ?,
+	purchase_date = ?, warranty_expires_on = ?, end_of_life_on = ?, version = version + 1
+	WHERE id = ?`
+
+// Update applies the name, brand, identifiers, lifecycle dates, state,
+// attributes and labels of d to the stored device, with the same checks as
+// the postgres backend. The location, owner, assignee and parent are left
+// as is.
+func (s *service) Update(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceById, d.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.Name != d.Name {
+		err = cur.ChangeDeviceName(d.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := resolveBrand(ctx, tx, d.BrandId, d.Brand, d.ModelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.BrandId != b.Id {
+		err = cur.ChangeDeviceBrand(b.Name)
+		if err != nil {
+			return nil, err
+		}
+		cur.BrandId = b.Id
+	}
+	cur.SerialNumber = strings.TrimSpace(d.SerialNumber)
+	cur.AssetTag = strings.TrimSpace(d.AssetTag)
+
+	if cur.State != d.State {
+		err = checkParentState(ctx, tx, cur)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cur.ChangeDeviceState(d.State)
+	if err != nil {
+		return nil, err
+	}
+
+	err = devices.ValidateLabels(d.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.ValidateDates()
+	if err != nil {
+		return nil, err
+	}
+	cur.Lifecycle = d.Lifecycle
+
+	attrs, err := marshalAttributes(d.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, updateDevice,
+		cur.Name, cur.BrandId, cur.State, attrs, nullId(cur.ModelId), nullString(cur.SerialNumber), nullString(cur.AssetTag),
+		cur.PurchaseDate, cur.WarrantyExpiresOn, cur.EndOfLifeOn, cur.Id,
+	)
+	if err != nil {
+		return nil, deviceWriteError(err, cur)
+	}
+
+	_, err = tx.ExecContext(ctx, deleteAllLabels, cur.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setLabels(ctx, tx, cur.Id, d.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, devices.ErrUpdateFailed
+	}
+
+	return result, nil
+}
+
+const hasParts = `SELECT EXISTS (SELECT 1 FROM devices WHERE parent_id = ? AND deleted_at IS NULL)`
+
+const deleteDevice = `UPDATE devices SET deleted_at = ?, version = version + 1 WHERE id = ?`
+
+// Delete moves the device to the trash. The in-use guard is checked against
+// the stored row, not d. Parts of an in-use device and devices that still
+// have parts cannot be deleted.
+func (s *service) Delete(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cur, err := scanDevice(tx.QueryRowContext(ctx, getDeviceById, d.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	err = cur.CheckVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if cur.IsDeviceInUse() {
+		return nil, devices.ErrDeviceInUse
+	}
+
+	err = checkParentState(ctx, tx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts bool
+	err = tx.QueryRowContext(ctx, hasParts, cur.Id).Scan(&parts)
+	if err != nil {
+		return nil, err
+	}
+	if parts {
+		return nil, devices.ErrDeviceHasParts
+	}
+
+	result, err := tx.ExecContext(ctx, deleteDevice, time.Now().UTC(), cur.Id)
+	if err != nil {
+		return nil, devices.ErrDeleteFailed
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, devices.ErrDeleteFailed
+	}
+
+	return result, nil
+}
+
+// Health checks the database by pinging it.
+func (s *service) Health() map[string]string {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	stats := make(map[string]string)
+
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		stats["status"] = "down"
+		stats["error"] = fmt.Sprintf("db down: %v", err)
+		return stats
+	}
+
+	stats["status"] = "up"
+	stats["message"] = "It's healthy"
+	stats["backend"] = "sqlite"
+	stats["in_use"] = strconv.Itoa(s.db.Stats().InUse)
+	return stats
+}
+
+// Close closes the database.
+func (s *service) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"devices_api/internal/devices/repotest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) devices.Repository {
+	repo, err := New(context.Background(), filepath.Join(t.TempDir(), "devices.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestLifecycleAndAttributes(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	purchased, err := devices.ParseDate("2024-05-01")
+	require.NoError(t, err)
+
+	d, err := repo.Create(ctx, devices.CreateDevice{
+		Name:       "Pixel 8",
+		Brand:      "Google",
+		Attributes: map[string]any{"ram_gb": 8.0},
+		Lifecycle:  devices.Lifecycle{PurchaseDate: &purchased},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01", d.PurchaseDate.String())
+	assert.Nil(t, d.WarrantyExpiresOn)
+	assert.Equal(t, map[string]any{"ram_gb": 8.0}, d.Attributes)
+	assert.Nil(t, d.Labels)
+
+	f, err := devices.ParseDeviceFilter("attr.ram_gb>=8")
+	require.NoError(t, err)
+	finder := repo.(devices.Finder)
+	dd, err := finder.Find(ctx, f)
+	assert.NoError(t, err)
+	assert.Len(t, dd, 1)
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.db")
+	ctx := context.Background()
+
+	repo, err := New(ctx, path)
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, devices.CreateDevice{Name: "Pixel 8", Brand: "Google", SerialNumber: "SN-1"})
+	require.NoError(t, err)
+	require.NoError(t, repo.Close())
+
+	// Migrations already applied are skipped.
+	repo, err = New(ctx, path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	dd, err := repo.All(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, dd, 1)
+
+	_, err = repo.Create(ctx, devices.CreateDevice{Name: "Pixel 9", Brand: "Google", SerialNumber: "SN-1"})
+	assert.Equal(t, &devices.ErrDuplicateField{Field: "serial_number", Value: "SN-1"}, err)
+}
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, newTestRepository)
+}
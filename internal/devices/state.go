@@ -0,0 +1,146 @@
+package devices
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidState = errors.New("invalid device state")
+
+// transitions declares the device state machine. Each key lists the states
+// a device may move to from that state.
+var transitions = map[DeviceState][]DeviceState{
+	Inactive:    {Available, Maintenance},
+	Available:   {InUse, Inactive, Maintenance},
+	InUse:       {Available},
+	Maintenance: {Available, Inactive},
+}
+
+// ErrInvalidTransition is returned when a device is asked to move to a state
+// that is not reachable from its current one.
+type ErrInvalidTransition struct {
+	From DeviceState
+	To   DeviceState
+}
+
+func (e *ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("invalid device state transition from %s to %s", e.From, e.To)
+}
+
+// stateNames holds the names of the states in the API. They are stable,
+// unlike the numbers, which follow the declaration order.
+var stateNames = map[DeviceState]string{
+	Available:   "available",
+	InUse:       "in_use",
+	Inactive:    "inactive",
+	Maintenance: "maintenance",
+}
+
+// StateNames lists the API names of every state in declaration order.
+func StateNames() []string {
+	names := make([]string, len(stateNames))
+	for ds, name := range stateNames {
+		names[ds] = name
+	}
+	return names
+}
+
+// ParseDeviceState accepts the name of a state, such as "in_use", or for
+// older clients its number.
+func ParseDeviceState(s string) (DeviceState, error) {
+	for ds, name := range stateNames {
+		if s == name {
+			return ds, nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err == nil && DeviceState(n).IsValid() {
+		return DeviceState(n), nil
+	}
+
+	return 0, fmt.Errorf("%w %q, want one of %s", ErrInvalidState, s, strings.Join(StateNames(), ", "))
+}
+
+// MarshalText writes the API name of the state, which is also how states
+// appear in JSON, including as map keys.
+func (ds DeviceState) MarshalText() ([]byte, error) {
+	name, ok := stateNames[ds]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrInvalidState, int(ds))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText accepts what ParseDeviceState accepts.
+func (ds *DeviceState) UnmarshalText(b []byte) error {
+	parsed, err := ParseDeviceState(string(b))
+	if err != nil {
+		return err
+	}
+	*ds = parsed
+	return nil
+}
+
+// UnmarshalJSON accepts a state name or, for older clients, a number.
+func (ds *DeviceState) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	} else if _, err := strconv.Atoi(s); err != nil {
+		return fmt.Errorf("%w %s, want one of %s", ErrInvalidState, s, strings.Join(StateNames(), ", "))
+	}
+	return ds.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer. States are stored by number.
+func (ds DeviceState) Value() (driver.Value, error) {
+	return int64(ds), nil
+}
+
+func (ds DeviceState) String() string {
+	switch ds {
+	case Available:
+		return "Available"
+	case InUse:
+		return "InUse"
+	case Inactive:
+		return "Inactive"
+	case Maintenance:
+		return "Maintenance"
+	}
+	return fmt.Sprintf("DeviceState(%d)", int(ds))
+}
+
+// IsValid reports whether ds is one of the declared device states.
+func (ds DeviceState) IsValid() bool {
+	_, ok := transitions[ds]
+	return ok
+}
+
+// CanTransitionTo reports whether the state machine allows moving from ds to
+// next. Staying in the same state is always allowed.
+func (ds DeviceState) CanTransitionTo(next DeviceState) bool {
+	if !ds.IsValid() || !next.IsValid() {
+		return false
+	}
+	if ds == next {
+		return true
+	}
+	for _, s := range transitions[ds] {
+		if s == next {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStates returns the states reachable from ds.
+func (ds DeviceState) NextStates() []DeviceState {
+	next := make([]DeviceState, len(transitions[ds]))
+	copy(next, transitions[ds])
+	return next
+}
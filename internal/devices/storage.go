@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -12,13 +14,51 @@ var (
 	ErrUpdateFailed = errors.New("update failed")
 	ErrDeleteFailed = errors.New("delete failed")
 	ErrDeviceInUse  = errors.New("cannot delete device while in use state")
+
+	// ErrDeviceInUseChange is wrapped by the errors refusing to rename or
+	// rebrand an in-use device.
+	ErrDeviceInUseChange = errors.New("while in use")
 )
 
-// CreateDevice represents the model to create a new device.
+// ErrDuplicateField is returned when a write would repeat the value of a
+// unique field, such as the serial number of a device, that another record
+// already has. It matches ErrDuplicate with errors.Is.
+type ErrDuplicateField struct {
+	Field string
+	Value string
+}
+
+func (e *ErrDuplicateField) Error() string {
+	return fmt.Sprintf("%s %q already exists", e.Field, e.Value)
+}
+
+func (e *ErrDuplicateField) Unwrap() error {
+	return ErrDuplicate
+}
+
+// CreateDevice represents the model to create a new device. The brand is
+// taken from BrandId when set, otherwise Brand is resolved by name or alias
+// and created in the catalog if it is unknown. When only ModelId is given
+// the device gets the brand of the model. SerialNumber and AssetTag are
+// optional but unique across devices. LocationId, OwnerId and AssigneeId
+// are the initial placement and ownership; later changes go through moves,
+// assignments and ownership transfers. The Lifecycle dates must pass
+// Lifecycle.ValidateDates.
 type CreateDevice struct {
-	Name  string      `json:"name"`
-	Brand string      `json:"brand"`
-	State DeviceState `json:"state"`
+	Name         string            `json:"name"`
+	Brand        string            `json:"brand"`
+	BrandId      int64             `json:"brand_id,omitempty"`
+	ModelId      int64             `json:"model_id,omitempty"`
+	SerialNumber string            `json:"serial_number,omitempty"`
+	AssetTag     string            `json:"asset_tag,omitempty"`
+	LocationId   int64             `json:"location_id,omitempty"`
+	OwnerId      int64             `json:"owner_id,omitempty"`
+	AssigneeId   int64             `json:"assignee_id,omitempty"`
+	State        DeviceState       `json:"state"`
+	Attributes   map[string]any    `json:"attributes,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+
+	Lifecycle
 }
 
 // Repository represents the interface contract for the Repository design pattern
@@ -26,26 +66,195 @@ type CreateDevice struct {
 type Repository interface {
 	Writer
 	Reader
-	Service
+	Database
 }
 
 // Writer represents the behaviour for writing data to repository.
+//
+// Update and Delete only succeed if the stored device is at the expected
+// version, otherwise they return an *ErrVersionConflict. Pass AnyVersion to
+// skip the check.
 type Writer interface {
 	Create(ctx context.Context, cd CreateDevice) (*Device, error)
-	Update(ctx context.Context, d Device) (sql.Result, error)
-	Delete(ctx context.Context, d Device) (sql.Result, error)
+	Update(ctx context.Context, d Device, version int64) (sql.Result, error)
+	Delete(ctx context.Context, d Device, version int64) (sql.Result, error)
 }
 
 // Reader represents the behaviour for reading data from repository.
 type Reader interface {
 	GetById(ctx context.Context, id int64) (*Device, error)
+	// GetByBrand accepts a brand id, name, slug or alias.
 	GetByBrand(ctx context.Context, b string) ([]Device, error)
 	GetByState(ctx context.Context, s DeviceState) ([]Device, error)
+	GetByAssetTag(ctx context.Context, tag string) (*Device, error)
 	All(ctx context.Context) ([]Device, error)
 }
 
-// Service represents a service that interacts with a database.
-type Service interface {
+// Finder represents the behaviour for listing devices matching a filter.
+type Finder interface {
+	Find(ctx context.Context, f DeviceFilter) ([]Device, error)
+}
+
+// LabelRepository represents the behaviour for changing the labels of a
+// device without a full update.
+type LabelRepository interface {
+	// UpdateLabels sets the labels in set and removes the keys in remove,
+	// returning the resulting labels of the device.
+	UpdateLabels(ctx context.Context, deviceId int64, set map[string]string, remove []string) (map[string]string, error)
+}
+
+// BrandRepository represents the behaviour for managing the brand catalog.
+type BrandRepository interface {
+	CreateBrand(ctx context.Context, cb CreateBrand) (*Brand, error)
+	// GetBrand accepts a brand id, name, slug or alias.
+	GetBrand(ctx context.Context, b string) (*Brand, error)
+	Brands(ctx context.Context) ([]Brand, error)
+	// RenameBrand changes the canonical name of a brand. The previous slug
+	// is kept as an alias.
+	RenameBrand(ctx context.Context, id int64, name string) (*Brand, error)
+	DeleteBrand(ctx context.Context, id int64) error
+	AddBrandAlias(ctx context.Context, id int64, alias string) (*Brand, error)
+	RemoveBrandAlias(ctx context.Context, id int64, alias string) (*Brand, error)
+}
+
+// ModelRepository represents the behaviour for managing the models of the
+// brand catalog.
+type ModelRepository interface {
+	CreateModel(ctx context.Context, brandId int64, cm CreateModel) (*Model, error)
+	GetModel(ctx context.Context, id int64) (*Model, error)
+	Models(ctx context.Context, brandId int64) ([]Model, error)
+	DeleteModel(ctx context.Context, id int64) error
+	GetByModel(ctx context.Context, modelId int64) ([]Device, error)
+	// Inventory counts the devices per model. A brandId of 0 includes
+	// every brand.
+	Inventory(ctx context.Context, brandId int64) ([]ModelInventory, error)
+}
+
+// LocationRepository represents the behaviour for managing the location tree
+// and moving devices within it.
+type LocationRepository interface {
+	CreateLocation(ctx context.Context, cl CreateLocation) (*Location, error)
+	GetLocation(ctx context.Context, id int64) (*Location, error)
+	Locations(ctx context.Context) ([]Location, error)
+	UpdateLocation(ctx context.Context, id int64, ul UpdateLocation) (*Location, error)
+	DeleteLocation(ctx context.Context, id int64) error
+	// MoveDevice moves a device to another location if it is at the
+	// expected version, see Writer.
+	MoveDevice(ctx context.Context, deviceId int64, m Move, version int64) (*Device, error)
+	// GetByLocation lists the devices at a location, and with
+	// includeChildren also those anywhere below it.
+	GetByLocation(ctx context.Context, locationId int64, includeChildren bool) ([]Device, error)
+}
+
+// DirectoryRepository represents the behaviour for managing users and
+// teams.
+type DirectoryRepository interface {
+	CreateUser(ctx context.Context, cu CreateUser) (*User, error)
+	GetUser(ctx context.Context, id int64) (*User, error)
+	Users(ctx context.Context) ([]User, error)
+	CreateTeam(ctx context.Context, ct CreateTeam) (*Team, error)
+	GetTeam(ctx context.Context, id int64) (*Team, error)
+	Teams(ctx context.Context) ([]Team, error)
+	AddTeamMember(ctx context.Context, teamId int64, m Membership) error
+	RemoveTeamMember(ctx context.Context, teamId, userId int64) error
+	TeamMembers(ctx context.Context, teamId int64) ([]User, error)
+}
+
+// OwnershipRepository represents the behaviour for assigning devices to
+// users and transferring them between teams.
+type OwnershipRepository interface {
+	// AssignDevice sets the assignee of a device if it is at the expected
+	// version, see Writer.
+	AssignDevice(ctx context.Context, deviceId int64, a Assignment, version int64) (*Device, error)
+	GetByOwner(ctx context.Context, teamId int64) ([]Device, error)
+	GetByAssignee(ctx context.Context, userId int64) ([]Device, error)
+	RequestTransfer(ctx context.Context, deviceId int64, ct CreateTransfer) (*Transfer, error)
+	Transfers(ctx context.Context, f TransferFilter) ([]Transfer, error)
+	// ResolveTransfer accepts, rejects or cancels a pending transfer on
+	// behalf of the team in tr, see Transfer.Resolve. The user in ctx, see
+	// WithUser, must be a member of that team. An accepted transfer changes
+	// the owner of the device.
+	ResolveTransfer(ctx context.Context, transferId int64, tr TransferResolution, status TransferStatus) (*Transfer, error)
+}
+
+// GroupRepository represents the behaviour for managing device groups and
+// changing every member of a group at once.
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, cg CreateGroup) (*Group, error)
+	GetGroup(ctx context.Context, id int64) (*Group, error)
+	Groups(ctx context.Context) ([]Group, error)
+	UpdateGroup(ctx context.Context, id int64, cg CreateGroup) (*Group, error)
+	DeleteGroup(ctx context.Context, id int64) error
+	AddGroupMembers(ctx context.Context, groupId int64, deviceIds []int64) (*Group, error)
+	RemoveGroupMember(ctx context.Context, groupId int64, deviceId int64) (*Group, error)
+	// GroupMembers lists the static members of a group together with the
+	// devices matching its filter.
+	GroupMembers(ctx context.Context, groupId int64) ([]Device, error)
+	// ApplyToGroup applies the action to every member in one transaction.
+	// If an atomic action fails for any member it is rolled back and
+	// ErrGroupActionFailed is returned along with the report.
+	ApplyToGroup(ctx context.Context, groupId int64, a GroupAction) (*GroupReport, error)
+}
+
+// CompositionRepository represents the behaviour for assembling devices
+// from parts.
+type CompositionRepository interface {
+	// SetParent makes a device part of another one, or detaches it, if it
+	// is at the expected version, see Writer.
+	SetParent(ctx context.Context, deviceId int64, a Attach, version int64) (*Device, error)
+	// DeviceTree returns a device with all of its parts, recursively.
+	DeviceTree(ctx context.Context, id int64) (*DeviceNode, error)
+}
+
+// HistoryReader represents the behaviour for reading the change history of
+// devices from repository.
+type HistoryReader interface {
+	History(ctx context.Context, deviceId int64, f HistoryFilter) ([]HistoryEntry, error)
+}
+
+// LeaseRepository represents the behaviour for checking devices out and in.
+type LeaseRepository interface {
+	Checkout(ctx context.Context, deviceId int64, c Checkout) (*Lease, error)
+	Checkin(ctx context.Context, deviceId int64) (*Lease, error)
+	// ExpireLeases checks in every device whose lease was due before now
+	// and returns the expired leases. A lease that cannot be expired does
+	// not hold back the others; its error is returned along with them.
+	ExpireLeases(ctx context.Context, now time.Time) ([]Lease, error)
+}
+
+// ReservationRepository represents the behaviour for booking devices for
+// future time windows.
+type ReservationRepository interface {
+	Reserve(ctx context.Context, deviceId int64, cr CreateReservation) (*Reservation, error)
+	Reservations(ctx context.Context, f ReservationFilter) ([]Reservation, error)
+	CancelReservation(ctx context.Context, deviceId int64, reservationId int64) error
+}
+
+// MaintenanceRepository represents the behaviour for scheduling maintenance
+// windows for devices.
+type MaintenanceRepository interface {
+	ScheduleMaintenance(ctx context.Context, deviceId int64, cm CreateMaintenance) (*MaintenanceWindow, error)
+	MaintenanceWindows(ctx context.Context, f MaintenanceFilter) ([]MaintenanceWindow, error)
+	// CancelMaintenance removes a window that has not started yet.
+	CancelMaintenance(ctx context.Context, deviceId int64, windowId int64) error
+	// ApplyMaintenance starts the windows due at now and ends the elapsed
+	// ones, returning the windows that changed. A window that cannot be
+	// applied does not hold back the others; its error is returned along
+	// with them.
+	ApplyMaintenance(ctx context.Context, now time.Time) ([]MaintenanceWindow, error)
+}
+
+// TrashRepository represents the behaviour for soft-deleted devices.
+type TrashRepository interface {
+	Trash(ctx context.Context) ([]Device, error)
+	Restore(ctx context.Context, id int64) (*Device, error)
+	// Purge permanently removes devices deleted before the given time and
+	// returns how many were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// Database represents the connection of a repository to its database.
+type Database interface {
 	// Health returns a map of health status information.
 	// The keys and values in the map are service-specific.
 	Health() map[string]string
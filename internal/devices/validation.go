@@ -0,0 +1,295 @@
+package devices
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Length limits of the free-text device fields, in characters.
+const (
+	MaxNameLength       = 100
+	MaxBrandLength      = 100
+	MaxIdentifierLength = 64
+)
+
+// identifierPattern restricts serial numbers and asset tags to what is
+// printed on labels and read by barcode scanners.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_./]*$`)
+
+// FieldError describes why the value of a single field was rejected. Field
+// is the JSON name, with nested fields joined by dots, as in "labels.team".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrValidation is returned when a request fails validation. It lists every
+// bad field, not just the first one.
+type ErrValidation struct {
+	Fields []FieldError
+}
+
+func (e *ErrValidation) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// validator collects field errors.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// text checks a free-text field: present if required, at most max
+// characters and without control characters.
+func (v *validator) text(field, s string, required bool, max int) {
+	switch {
+	case strings.TrimSpace(s) == "":
+		if required {
+			v.add(field, "is required")
+		}
+	case !utf8.ValidString(s):
+		v.add(field, "must be valid UTF-8")
+	case utf8.RuneCountInString(s) > max:
+		v.add(field, "must be at most %d characters", max)
+	case strings.IndexFunc(s, unicode.IsControl) >= 0:
+		v.add(field, "must not contain control characters")
+	}
+}
+
+// identifier checks an optional serial number or asset tag.
+func (v *validator) identifier(field, s string) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+	case len(s) > MaxIdentifierLength:
+		v.add(field, "must be at most %d characters", MaxIdentifierLength)
+	case !identifierPattern.MatchString(s):
+		v.add(field, "may only contain letters, digits, '-', '_', '.' and '/'")
+	}
+}
+
+func (v *validator) state(field string, ds DeviceState) {
+	if !ds.IsValid() {
+		v.add(field, "must be one of %s", strings.Join(StateNames(), ", "))
+	}
+}
+
+// labels checks every label, sorted by key so the errors are stable.
+func (v *validator) labels(field string, labels map[string]string) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := ValidateLabelKey(k); err != nil {
+			v.add(field+"."+k, "%v", err)
+			continue
+		}
+		if err := ValidateLabelValue(labels[k]); err != nil {
+			v.add(field+"."+k, "%v", err)
+		}
+	}
+}
+
+func (v *validator) attributes(field string, attrs map[string]any) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if !attributeKeyPattern.MatchString(k) {
+			v.add(field+"."+k, "attribute keys may only contain up to 64 letters, digits, '-' and '_'")
+		}
+	}
+}
+
+// lifecycle applies the checks of Lifecycle.ValidateDates per field.
+func (v *validator) lifecycle(l Lifecycle) {
+	if l.PurchaseDate == nil {
+		return
+	}
+	if l.WarrantyExpiresOn != nil && l.WarrantyExpiresOn.Before(l.PurchaseDate.Time) {
+		v.add("warranty_expires_on", "must not be before the purchase date %s", l.PurchaseDate)
+	}
+	if l.EndOfLifeOn != nil && !l.EndOfLifeOn.After(l.PurchaseDate.Time) {
+		v.add("end_of_life_on", "must be after the purchase date %s", l.PurchaseDate)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ErrValidation{Fields: v.fields}
+}
+
+// Validate checks the fields of a new device. The brand may be left out
+// when the device references a brand or model by id.
+func (cd CreateDevice) Validate() error {
+	var v validator
+
+	v.text("name", cd.Name, true, MaxNameLength)
+	v.text("brand", cd.Brand, cd.BrandId == 0 && cd.ModelId == 0, MaxBrandLength)
+	v.identifier("serial_number", cd.SerialNumber)
+	v.identifier("asset_tag", cd.AssetTag)
+	v.state("state", cd.State)
+	v.attributes("attributes", cd.Attributes)
+	v.labels("labels", cd.Labels)
+	v.lifecycle(cd.Lifecycle)
+
+	return v.err()
+}
+
+// Validate checks the writable fields of a device sent as an update.
+func (d *Device) Validate() error {
+	var v validator
+
+	v.text("name", d.Name, true, MaxNameLength)
+	v.text("brand", d.Brand, d.BrandId == 0 && d.ModelId == 0, MaxBrandLength)
+	v.identifier("serial_number", d.SerialNumber)
+	v.identifier("asset_tag", d.AssetTag)
+	v.state("state", d.State)
+	v.attributes("attributes", d.Attributes)
+	v.labels("labels", d.Labels)
+	v.lifecycle(d.Lifecycle)
+
+	return v.err()
+}
+
+// Decode reads a JSON request body into v, which must point to a struct or
+// a map. Each field is decoded on its own, so the returned *ErrValidation
+// lists every unknown struct field, every field of the wrong type and, when
+// v has a Validate method, every field it rejects. Malformed JSON is
+// returned as is.
+func Decode(r io.Reader, v any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	err = json.Unmarshal(body, &raw)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &ErrValidation{Fields: []FieldError{{Field: "", Message: "body must be a JSON object"}}}
+		}
+		return err
+	}
+
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var val validator
+	if t := reflect.TypeOf(v).Elem(); t.Kind() != reflect.Map {
+		known := jsonFields(t)
+		for _, k := range keys {
+			if !known[strings.ToLower(k)] {
+				val.add(k, "is not a known field")
+			}
+		}
+		if val.err() != nil {
+			return val.err()
+		}
+	}
+
+	bad := map[string]bool{}
+	for _, k := range keys {
+		field, err := json.Marshal(map[string]json.RawMessage{k: raw[k]})
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(field, v)
+
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			name := typeErr.Field
+			if name == "" {
+				name = k
+			}
+			val.add(name, "must be a %s", typeErr.Type)
+		case errors.Is(err, ErrInvalidState):
+			val.add(k, "must be one of %s", strings.Join(StateNames(), ", "))
+		case err != nil:
+			// The body is valid JSON, so this is a value rejected by an
+			// UnmarshalJSON method, such as a malformed date.
+			val.add(k, "%v", err)
+		default:
+			continue
+		}
+		bad[strings.ToLower(k)] = true
+	}
+
+	// Fields that could not be decoded are left out of Validate, which
+	// would only report them again as missing.
+	if vv, ok := v.(interface{ Validate() error }); ok {
+		var ve *ErrValidation
+		if errors.As(vv.Validate(), &ve) {
+			for _, f := range ve.Fields {
+				top, _, _ := strings.Cut(f.Field, ".")
+				if !bad[top] {
+					val.fields = append(val.fields, f)
+				}
+			}
+		}
+	}
+	return val.err()
+}
+
+// jsonFields returns the lower-cased JSON names of the fields of struct
+// type t, including those of embedded structs. Like encoding/json, Decode
+// matches names case-insensitively.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k := range jsonFields(ft) {
+				fields[k] = true
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = true
+	}
+	return fields
+}
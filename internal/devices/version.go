@@ -0,0 +1,33 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AnyVersion disables the optimistic concurrency check of a write.
+const AnyVersion int64 = 0
+
+// ErrConcurrentUpdate is returned when a write without an expected version
+// kept conflicting with concurrent writes to the same device.
+var ErrConcurrentUpdate = errors.New("device is being changed concurrently, try again")
+
+// ErrVersionConflict is returned when a write expected a different version of
+// the device than the one stored.
+type ErrVersionConflict struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *ErrVersionConflict) Error() string {
+	return fmt.Sprintf("device version conflict: expected version %d, found %d", e.Expected, e.Actual)
+}
+
+// CheckVersion returns an *ErrVersionConflict unless v is AnyVersion or the
+// device's current version.
+func (d *Device) CheckVersion(v int64) error {
+	if v != AnyVersion && v != d.Version {
+		return &ErrVersionConflict{Expected: v, Actual: d.Version}
+	}
+	return nil
+}
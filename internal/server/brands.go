@@ -0,0 +1,224 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// renameBrand is the request payload to rename a brand.
+type renameBrand struct {
+	Name string `json:"name"`
+}
+
+// brandAlias is the request payload to add a brand alias.
+type brandAlias struct {
+	Alias string `json:"alias"`
+}
+
+// CreateBrand swagger:route POST /brands brands createBrand
+//
+// Adds a brand to the catalog.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: brand
+//	    400: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateBrand(w http.ResponseWriter, r *http.Request) {
+	var cb devices.CreateBrand
+	err := devices.Decode(r.Body, &cb)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.brands.CreateBrand(r.Context(), cb)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, b)
+}
+
+// AllBrands swagger:route GET /brands brands allBrands
+//
+// Get all brands of the catalog.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []brand
+//	    500: internalServerError
+func (s *Server) AllBrands(w http.ResponseWriter, r *http.Request) {
+	bb, err := s.brands.Brands(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, bb)
+}
+
+// BrandById swagger:route GET /brands/{id} brands brandById
+//
+// Get a brand by its ID, slug or one of its aliases.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: brand
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) BrandById(w http.ResponseWriter, r *http.Request) {
+	b, err := s.brands.GetBrand(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, b)
+}
+
+// RenameBrand swagger:route PUT /brands/{id} brands renameBrand
+//
+// Renames a brand on every device that uses it. The previous name remains
+// usable as an alias.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: brand
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) RenameBrand(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rb renameBrand
+	err = devices.Decode(r.Body, &rb)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.brands.RenameBrand(r.Context(), id, rb.Name)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, b)
+}
+
+// DeleteBrand swagger:route DELETE /brands/{id} brands deleteBrand
+//
+// Deletes a brand that no device uses anymore.
+//
+// Responses:
+//
+//	default: genericError
+//	    204: noContent
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) DeleteBrand(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.brands.DeleteBrand(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddBrandAlias swagger:route POST /brands/{id}/aliases brands addBrandAlias
+//
+// Adds an alternative spelling that resolves to the brand.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: brand
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) AddBrandAlias(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ba brandAlias
+	err = devices.Decode(r.Body, &ba)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.brands.AddBrandAlias(r.Context(), id, ba.Alias)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, b)
+}
+
+// RemoveBrandAlias swagger:route DELETE /brands/{id}/aliases/{alias} brands removeBrandAlias
+//
+// Removes an alias from the brand.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: brand
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) RemoveBrandAlias(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := s.brands.RemoveBrandAlias(r.Context(), id, chi.URLParam(r, "alias"))
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, b)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// SetDeviceParent swagger:route PUT /devices/{id}/parent devices setDeviceParent
+//
+// Makes a device part of another one, such as a phone of a test rig, or
+// detaches it with a zero parent_id. A device cannot become a part of one
+// of its own parts, and assemblies cannot change while in use. An If-Match
+// header with the device ETag makes the change conditional.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: device
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    412: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) SetDeviceParent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var a devices.Attach
+	err = devices.Decode(r.Body, &a)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.composition.SetParent(r.Context(), id, a, version)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
+}
+
+// DeviceTree swagger:route GET /devices/{id}/tree devices deviceTree
+//
+// Get a device with all of its parts, recursively.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: deviceNode
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) DeviceTree(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n, err := s.composition.DeviceTree(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, n)
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateUser swagger:route POST /users directory createUser
+//
+// Adds a user devices can be assigned to.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: user
+//	    400: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var cu devices.CreateUser
+	err := devices.Decode(r.Body, &cu)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	u, err := s.directory.CreateUser(r.Context(), cu)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, u)
+}
+
+// AllUsers swagger:route GET /users directory allUsers
+//
+// Get all users.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []user
+//	    500: internalServerError
+func (s *Server) AllUsers(w http.ResponseWriter, r *http.Request) {
+	uu, err := s.directory.Users(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, uu)
+}
+
+// UserById swagger:route GET /users/{id} directory userById
+//
+// Get a user by its ID.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: user
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) UserById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := s.directory.GetUser(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, u)
+}
+
+// CreateTeam swagger:route POST /teams directory createTeam
+//
+// Adds a team that can own devices.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: team
+//	    400: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var ct devices.CreateTeam
+	err := devices.Decode(r.Body, &ct)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.directory.CreateTeam(r.Context(), ct)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, t)
+}
+
+// AllTeams swagger:route GET /teams directory allTeams
+//
+// Get all teams.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []team
+//	    500: internalServerError
+func (s *Server) AllTeams(w http.ResponseWriter, r *http.Request) {
+	tt, err := s.directory.Teams(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, tt)
+}
+
+// TeamById swagger:route GET /teams/{id} directory teamById
+//
+// Get a team by its ID.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: team
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) TeamById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.directory.GetTeam(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, t)
+}
+
+// AddTeamMember swagger:route POST /teams/{id}/members directory addTeamMember
+//
+// Adds a user to a team. Members act for the team, for example when
+// resolving ownership transfers.
+//
+// Responses:
+//
+//	default: genericError
+//	    204:
+//	    400: genericError
+//	    404: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var m devices.Membership
+	err = devices.Decode(r.Body, &m)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	err = s.directory.AddTeamMember(r.Context(), id, m)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveTeamMember swagger:route DELETE /teams/{id}/members/{userId} directory removeTeamMember
+//
+// Removes a user from a team.
+//
+// Responses:
+//
+//	default: genericError
+//	    204:
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId, err := strconv.ParseInt(chi.URLParam(r, "userId"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.directory.RemoveTeamMember(r.Context(), id, userId)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TeamMembers swagger:route GET /teams/{id}/members directory teamMembers
+//
+// Get the members of a team.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []user
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) TeamMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uu, err := s.directory.TeamMembers(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, uu)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New("If-Match must be * or a quoted device version")
+
+// setETag exposes the device version as a strong entity tag.
+func setETag(w http.ResponseWriter, d *devices.Device) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(d.Version, 10)))
+}
+
+// ifMatchVersion returns the device version expected by the If-Match header.
+// A missing header or * matches any version.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return devices.AnyVersion, nil
+	}
+
+	tag, err := strconv.Unquote(h)
+	if err != nil {
+		return 0, errInvalidIfMatch
+	}
+
+	v, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || v == devices.AnyVersion {
+		return 0, errInvalidIfMatch
+	}
+	return v, nil
+}
@@ -0,0 +1,12 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"net/http"
+)
+
+// parseDeviceFilter reads the device filters from the query string, see
+// devices.ParseDeviceFilter.
+func parseDeviceFilter(r *http.Request) (devices.DeviceFilter, error) {
+	return devices.ParseDeviceFilter(r.URL.RawQuery)
+}
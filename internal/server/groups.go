@@ -0,0 +1,305 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateGroup swagger:route POST /groups groups createGroup
+//
+// Creates a named group of devices. Devices join it by id, or by matching
+// its filter, which uses the query string syntax of GET /devices.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: group
+//	    400: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var cg devices.CreateGroup
+	err := devices.Decode(r.Body, &cg)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.groups.CreateGroup(r.Context(), cg)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, g)
+}
+
+// AllGroups swagger:route GET /groups groups allGroups
+//
+// Get every group by name.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []group
+//	    500: internalServerError
+func (s *Server) AllGroups(w http.ResponseWriter, r *http.Request) {
+	gg, err := s.groups.Groups(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, gg)
+}
+
+// GroupById swagger:route GET /groups/{id} groups groupById
+//
+// Get a group by its ID.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: group
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) GroupById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.groups.GetGroup(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, g)
+}
+
+// UpdateGroup swagger:route PUT /groups/{id} groups updateGroup
+//
+// Changes the name, description or filter of a group.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: group
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cg devices.CreateGroup
+	err = devices.Decode(r.Body, &cg)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.groups.UpdateGroup(r.Context(), id, cg)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, g)
+}
+
+// DeleteGroup swagger:route DELETE /groups/{id} groups deleteGroup
+//
+// Deletes a group. Its devices are not affected.
+//
+// Responses:
+//
+//	default: genericError
+//	    204: noContent
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.groups.DeleteGroup(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddGroupMembers swagger:route POST /groups/{id}/members groups addGroupMembers
+//
+// Adds devices to a group by id.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: group
+//	    400: genericError
+//	    404: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) AddGroupMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var m devices.GroupMembers
+	err = devices.Decode(r.Body, &m)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.groups.AddGroupMembers(r.Context(), id, m.DeviceIds)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, g)
+}
+
+// RemoveGroupMember swagger:route DELETE /groups/{id}/members/{deviceId} groups removeGroupMember
+//
+// Removes a device added by id from a group. Devices matching the filter of
+// the group stay members.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: group
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	did, err := strconv.ParseInt(chi.URLParam(r, "deviceId"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	g, err := s.groups.RemoveGroupMember(r.Context(), id, did)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, g)
+}
+
+// GroupDevices swagger:route GET /groups/{id}/devices groups groupDevices
+//
+// Get the members of a group, both those added by id and those matching
+// its filter.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) GroupDevices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dd, err := s.groups.GroupMembers(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// ApplyToGroup swagger:route POST /groups/{id}/actions groups applyToGroup
+//
+// Changes the state or labels of every member of a group, or offers them
+// all to another team, in one transaction. The report lists the outcome per
+// device; members that could not be changed, for example because they are
+// in use, are left as they were. An atomic action that fails for any member
+// is rolled back and answered with 409 and the report.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: groupReport
+//	    400: genericError
+//	    404: genericError
+//	    409: groupReport
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) ApplyToGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var a devices.GroupAction
+	err = devices.Decode(r.Body, &a)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.groups.ApplyToGroup(r.Context(), id, a)
+	if errors.Is(err, devices.ErrGroupActionFailed) && report != nil {
+		log.Println(w, r, err.Error())
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, report)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, report)
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultLeaseReapInterval   = time.Minute
+	defaultTrashPurgeInterval  = time.Hour
+	defaultTrashRetention      = 30 * 24 * time.Hour
+	defaultMaintenanceInterval = time.Minute
+	defaultLifecycleInterval   = 24 * time.Hour
+	defaultLifecycleWindow     = 90 * 24 * time.Hour
+)
+
+// startJobs runs the background jobs of the server until ctx is done.
+func (s *Server) startJobs(ctx context.Context) {
+	if s.leases != nil {
+		go runEvery(ctx, envDuration("LEASE_REAP_INTERVAL", defaultLeaseReapInterval), s.reapExpiredLeases)
+	}
+
+	if s.trash != nil {
+		retention := envDuration("TRASH_RETENTION", defaultTrashRetention)
+		go runEvery(ctx, envDuration("TRASH_PURGE_INTERVAL", defaultTrashPurgeInterval), s.purgeTrash(retention))
+	}
+
+	if s.maintenance != nil {
+		go runEvery(ctx, envDuration("MAINTENANCE_INTERVAL", defaultMaintenanceInterval), s.applyMaintenance)
+	}
+
+	if s.finder != nil {
+		window := envDuration("LIFECYCLE_WARNING_WINDOW", defaultLifecycleWindow)
+		go runEvery(ctx, envDuration("LIFECYCLE_CHECK_INTERVAL", defaultLifecycleInterval), s.lifecycleWarnings(window))
+	}
+}
+
+// runEvery calls job every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, job func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job(ctx)
+		}
+	}
+}
+
+// envDuration reads a duration such as "30s" from the environment, falling
+// back to def when the variable is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s %q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
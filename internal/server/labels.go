@@ -0,0 +1,59 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// UpdateDeviceLabels swagger:route PATCH /devices/{id}/labels devices updateDeviceLabels
+//
+// Adds, changes or removes labels of a device without a full update. The body
+// is a JSON merge patch: {"team": "qa", "env": null} sets team and removes
+// env. The resulting labels are returned.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: labels
+//	    400: genericError
+//	    404: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) UpdateDeviceLabels(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var patch devices.LabelPatch
+	err = devices.Decode(r.Body, &patch)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	set := make(map[string]string)
+	var remove []string
+	for k, v := range patch {
+		if v == nil {
+			remove = append(remove, k)
+			continue
+		}
+		set[k] = *v
+	}
+
+	labels, err := s.labels.UpdateLabels(r.Context(), id, set, remove)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, labels)
+}
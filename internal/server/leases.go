@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// leaseReaperActor is recorded in the device history for expired leases.
+const leaseReaperActor = "lease-reaper"
+
+// CheckoutDevice swagger:route POST /devices/{id}/checkout devices checkoutDevice
+//
+// Checks a device out to a holder until the expected return time. With
+// cascade set, the parts of the device are checked out along with it and
+// checked in with it again.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: lease
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CheckoutDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var c devices.Checkout
+	err = devices.Decode(r.Body, &c)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	l, err := s.leases.Checkout(r.Context(), id, c)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, l)
+}
+
+// CheckinDevice swagger:route POST /devices/{id}/checkin devices checkinDevice
+//
+// Returns a checked out device, making it available again.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: lease
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) CheckinDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := s.leases.Checkin(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, l)
+}
+
+// reapExpiredLeases checks in every device whose lease has expired.
+func (s *Server) reapExpiredLeases(ctx context.Context) {
+	ctx = devices.WithActor(ctx, leaseReaperActor)
+
+	// The leases that expired are returned even when others failed.
+	ll, err := s.leases.ExpireLeases(ctx, time.Now())
+	if err != nil {
+		log.Printf("lease reaper: %v", err)
+	}
+
+	for _, l := range ll {
+		log.Printf("lease reaper: lease %d of device %d held by %s expired at %s, device is available again",
+			l.Id, l.DeviceId, l.Holder, l.ExpectedReturnAt.Format(time.RFC3339))
+	}
+}
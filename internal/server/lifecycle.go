@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"log"
+	"time"
+)
+
+// lifecycleWarnings logs a warning for every device whose warranty expires
+// or which reaches its end of life within window. Devices past either date
+// are reported on every run until the date is updated or they are deleted.
+func (s *Server) lifecycleWarnings(window time.Duration) func(context.Context) {
+	return func(ctx context.Context) {
+		now := time.Now()
+		today := devices.NewDate(now)
+		limit := devices.NewDate(now.Add(window))
+
+		dd, err := s.finder.Find(ctx, devices.DeviceFilter{WarrantyExpiresBefore: &limit})
+		if err != nil {
+			log.Printf("lifecycle: %v", err)
+			return
+		}
+
+		for _, d := range dd {
+			log.Printf("lifecycle: warranty of device %d (%s %s) %s on %s",
+				d.Id, d.Brand, d.Name, tense(d.WarrantyExpiresOn, today, "expired", "expires"), d.WarrantyExpiresOn)
+		}
+
+		dd, err = s.finder.Find(ctx, devices.DeviceFilter{EndOfLifeBefore: &limit})
+		if err != nil {
+			log.Printf("lifecycle: %v", err)
+			return
+		}
+
+		for _, d := range dd {
+			log.Printf("lifecycle: device %d (%s %s) %s end of life on %s",
+				d.Id, d.Brand, d.Name, tense(d.EndOfLifeOn, today, "reached", "reaches"), d.EndOfLifeOn)
+		}
+	}
+}
+
+func tense(d *devices.Date, today devices.Date, past, future string) string {
+	if d.Before(today.Time) {
+		return past
+	}
+	return future
+}
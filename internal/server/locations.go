@@ -0,0 +1,244 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateLocation swagger:route POST /locations locations createLocation
+//
+// Adds a site, building, room or rack to the location tree.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: location
+//	    400: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	var cl devices.CreateLocation
+	err := devices.Decode(r.Body, &cl)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	l, err := s.locations.CreateLocation(r.Context(), cl)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, l)
+}
+
+// AllLocations swagger:route GET /locations locations allLocations
+//
+// Get every location of the tree, roots first.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []location
+//	    500: internalServerError
+func (s *Server) AllLocations(w http.ResponseWriter, r *http.Request) {
+	ll, err := s.locations.Locations(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, ll)
+}
+
+// LocationById swagger:route GET /locations/{id} locations locationById
+//
+// Get a location by its ID.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: location
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) LocationById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := s.locations.GetLocation(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, l)
+}
+
+// UpdateLocation swagger:route PUT /locations/{id} locations updateLocation
+//
+// Renames a location or moves it, with everything below it, under another
+// parent.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: location
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ul devices.UpdateLocation
+	err = devices.Decode(r.Body, &ul)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	l, err := s.locations.UpdateLocation(r.Context(), id, ul)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, l)
+}
+
+// DeleteLocation swagger:route DELETE /locations/{id} locations deleteLocation
+//
+// Deletes a location that holds no devices or other locations.
+//
+// Responses:
+//
+//	default: genericError
+//	    204: noContent
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.locations.DeleteLocation(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LocationDevices swagger:route GET /locations/{id}/devices locations locationDevices
+//
+// Get the devices at a location. With include_children=true the devices
+// anywhere below the location are included, for example everything in a
+// building.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) LocationDevices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var includeChildren bool
+	if v := r.URL.Query().Get("include_children"); v != "" {
+		includeChildren, err = strconv.ParseBool(v)
+		if err != nil {
+			log.Println(w, r, err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	dd, err := s.locations.GetByLocation(r.Context(), id, includeChildren)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// MoveDevice swagger:route POST /devices/{id}/move devices moveDevice
+//
+// Moves a device to another location. The move is recorded in the device
+// history. An If-Match header with the device ETag makes the move
+// conditional.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: device
+//	    400: genericError
+//	    404: genericError
+//	    412: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) MoveDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var m devices.Move
+	err = devices.Decode(r.Body, &m)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.locations.MoveDevice(r.Context(), id, m, version)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
+}
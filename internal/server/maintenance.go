@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// maintenanceActor is recorded in the device history for state changes made
+// by maintenance windows.
+const maintenanceActor = "maintenance-scheduler"
+
+// ScheduleMaintenance swagger:route POST /devices/{id}/maintenance devices scheduleMaintenance
+//
+// Schedules a maintenance window. The device enters Maintenance when the
+// window starts and returns to its previous state when it ends.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: maintenanceWindow
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) ScheduleMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cm devices.CreateMaintenance
+	err = devices.Decode(r.Body, &cm)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	mw, err := s.maintenance.ScheduleMaintenance(r.Context(), id, cm)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, mw)
+}
+
+// DeviceMaintenance swagger:route GET /devices/{id}/maintenance devices deviceMaintenance
+//
+// Get the maintenance windows of a device. The optional from and to query
+// parameters (RFC 3339) restrict the result to windows overlapping [from, to).
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []maintenanceWindow
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) DeviceMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.listMaintenance(w, r, id)
+}
+
+// AllMaintenance swagger:route GET /maintenance devices allMaintenance
+//
+// Get the maintenance windows of all devices. The optional from and to query
+// parameters (RFC 3339) restrict the result to windows overlapping [from, to).
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []maintenanceWindow
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) AllMaintenance(w http.ResponseWriter, r *http.Request) {
+	s.listMaintenance(w, r, 0)
+}
+
+func (s *Server) listMaintenance(w http.ResponseWriter, r *http.Request, deviceId int64) {
+	f := devices.MaintenanceFilter{DeviceId: deviceId}
+
+	var err error
+	f.From, err = parseTimeQuery(r, "from")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.To, err = parseTimeQuery(r, "to")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ww, err := s.maintenance.MaintenanceWindows(r.Context(), f)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, ww)
+}
+
+// CancelMaintenance swagger:route DELETE /devices/{id}/maintenance/{windowId} devices cancelMaintenance
+//
+// Cancels a maintenance window that has not started yet.
+//
+// Responses:
+//
+//	default: genericError
+//	    204:
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) CancelMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wid, err := strconv.ParseInt(chi.URLParam(r, "windowId"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.maintenance.CancelMaintenance(r.Context(), id, wid)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyMaintenance moves devices into and out of Maintenance as their
+// windows start and end.
+func (s *Server) applyMaintenance(ctx context.Context) {
+	ctx = devices.WithActor(ctx, maintenanceActor)
+
+	// The windows that changed are returned even when others failed.
+	ww, err := s.maintenance.ApplyMaintenance(ctx, time.Now())
+	if err != nil {
+		log.Printf("maintenance: %v", err)
+	}
+
+	for _, mw := range ww {
+		if mw.EndedAt != nil {
+			log.Printf("maintenance: window %d of device %d ended", mw.Id, mw.DeviceId)
+			continue
+		}
+		log.Printf("maintenance: window %d of device %d started, %s by %s until %s",
+			mw.Id, mw.DeviceId, mw.Reason, mw.Technician, mw.EndsAt.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// actorHeader carries the name of whoever is making the request. It is
+// recorded in the device history.
+const actorHeader = "X-Actor"
+
+// userHeader carries the directory id of the user making the request. It is
+// checked against team memberships, for example when resolving transfers.
+const userHeader = "X-User-Id"
+
+// actor stores the request actor and user in the request context.
+func actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a := r.Header.Get(actorHeader); a != "" {
+			r = r.WithContext(devices.WithActor(r.Context(), a))
+		}
+		if u := r.Header.Get(userHeader); u != "" {
+			id, err := strconv.ParseInt(u, 10, 64)
+			if err != nil || id <= 0 {
+				http.Error(w, fmt.Sprintf("invalid %s header %q", userHeader, u), http.StatusBadRequest)
+				return
+			}
+			r = r.WithContext(devices.WithUser(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// CreateModel swagger:route POST /brands/{id}/models brands createModel
+//
+// Adds a model to a brand.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: model
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) CreateModel(w http.ResponseWriter, r *http.Request) {
+	brandId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cm devices.CreateModel
+	err = devices.Decode(r.Body, &cm)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	m, err := s.models.CreateModel(r.Context(), brandId, cm)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, m)
+}
+
+// BrandModels swagger:route GET /brands/{id}/models brands brandModels
+//
+// Get the models of a brand.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []model
+//	    500: internalServerError
+func (s *Server) BrandModels(w http.ResponseWriter, r *http.Request) {
+	brandId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mm, err := s.models.Models(r.Context(), brandId)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, mm)
+}
+
+// ModelById swagger:route GET /models/{id} models modelById
+//
+// Get a model by its ID.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: model
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) ModelById(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := s.models.GetModel(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, m)
+}
+
+// DeleteModel swagger:route DELETE /models/{id} models deleteModel
+//
+// Deletes a model that no device uses anymore.
+//
+// Responses:
+//
+//	default: genericError
+//	    204: noContent
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.models.DeleteModel(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ModelDevices swagger:route GET /models/{id}/devices models modelDevices
+//
+// Get the devices of a model.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    500: internalServerError
+func (s *Server) ModelDevices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dd, err := s.models.GetByModel(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// ModelInventory swagger:route GET /models/inventory models modelInventory
+//
+// Counts the devices of each model by state. The optional brand_id query
+// parameter restricts the counts to the models of one brand.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []modelInventory
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) ModelInventory(w http.ResponseWriter, r *http.Request) {
+	var brandId int64
+	if v := r.URL.Query().Get("brand_id"); v != "" {
+		var err error
+		brandId, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Println(w, r, err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	inv, err := s.models.Inventory(r.Context(), brandId)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, inv)
+}
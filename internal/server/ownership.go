@@ -0,0 +1,259 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// AssignDevice swagger:route PUT /devices/{id}/assignee devices assignDevice
+//
+// Assigns a device to a user, or clears the assignee with a zero user_id.
+// An If-Match header with the device ETag makes the change conditional.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: device
+//	    400: genericError
+//	    404: genericError
+//	    412: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) AssignDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var a devices.Assignment
+	err = devices.Decode(r.Body, &a)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.ownership.AssignDevice(r.Context(), id, a, version)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
+}
+
+// TeamDevices swagger:route GET /teams/{id}/devices directory teamDevices
+//
+// Get the devices owned by a team.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    500: internalServerError
+func (s *Server) TeamDevices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dd, err := s.ownership.GetByOwner(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// UserDevices swagger:route GET /users/{id}/devices directory userDevices
+//
+// Get the devices assigned to a user.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    500: internalServerError
+func (s *Server) UserDevices(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dd, err := s.ownership.GetByAssignee(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// RequestTransfer swagger:route POST /devices/{id}/transfers devices requestTransfer
+//
+// Offers the ownership of a device to another team. The device keeps its
+// owner until the receiving team accepts the transfer.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: transfer
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) RequestTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var ct devices.CreateTransfer
+	err = devices.Decode(r.Body, &ct)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.ownership.RequestTransfer(r.Context(), id, ct)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, t)
+}
+
+// DeviceTransfers swagger:route GET /devices/{id}/transfers devices deviceTransfers
+//
+// Get the ownership transfers of a device. The optional status query
+// parameter restricts the result, for example to pending transfers.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []transfer
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) DeviceTransfers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.listTransfers(w, r, devices.TransferFilter{DeviceId: id})
+}
+
+// TeamTransfers swagger:route GET /teams/{id}/transfers directory teamTransfers
+//
+// Get the ownership transfers a team offers or is offered. The optional
+// status query parameter restricts the result, for example to pending
+// transfers.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []transfer
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) TeamTransfers(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.listTransfers(w, r, devices.TransferFilter{TeamId: id})
+}
+
+func (s *Server) listTransfers(w http.ResponseWriter, r *http.Request, f devices.TransferFilter) {
+	f.Status = devices.TransferStatus(r.URL.Query().Get("status"))
+	if f.Status != "" && !f.Status.IsValid() {
+		log.Println(w, r, "unknown transfer status", f.Status)
+		http.Error(w, fmt.Sprintf("unknown transfer status %q", f.Status), http.StatusBadRequest)
+		return
+	}
+
+	tt, err := s.ownership.Transfers(r.Context(), f)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, tt)
+}
+
+// resolveTransfer swagger:route POST /transfers/{id}/{resolution} devices resolveTransfer
+//
+// Accepts, rejects or cancels a pending ownership transfer, with resolution
+// one of accept, reject or cancel. Accepting makes the receiving team the
+// owner of the device. The body names the acting team as team_id: the
+// receiving team accepts or rejects, the offering team cancels. The user in
+// the X-User-Id header must be a member of that team.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: transfer
+//	    400: genericError
+//	    403: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) resolveTransfer(status devices.TransferStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			log.Println(w, r, err.Error())
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var tr devices.TransferResolution
+		err = devices.Decode(r.Body, &tr)
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+
+		t, err := s.ownership.ResolveTransfer(r.Context(), id, tr, status)
+		if err != nil {
+			writeError(w, r, err, statusFor(err))
+			return
+		}
+
+		render.JSON(w, r, t)
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"devices_api/internal/devices"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// ReserveDevice swagger:route POST /devices/{id}/reservations devices reserveDevice
+//
+// Books a device for a future time window.
+//
+// Responses:
+//
+//	default: genericError
+//	    201: reservation
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    422: validationError
+//	    500: internalServerError
+func (s *Server) ReserveDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cr devices.CreateReservation
+	err = devices.Decode(r.Body, &cr)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.reservations.Reserve(r.Context(), id, cr)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, res)
+}
+
+// DeviceReservations swagger:route GET /devices/{id}/reservations devices deviceReservations
+//
+// Get the reservations of a device. The optional from and to query
+// parameters (RFC 3339) restrict the result to windows overlapping [from, to).
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []reservation
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) DeviceReservations(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.listReservations(w, r, id)
+}
+
+// AllReservations swagger:route GET /reservations devices allReservations
+//
+// Get the reservations of all devices. The optional from and to query
+// parameters (RFC 3339) restrict the result to windows overlapping [from, to).
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []reservation
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) AllReservations(w http.ResponseWriter, r *http.Request) {
+	s.listReservations(w, r, 0)
+}
+
+func (s *Server) listReservations(w http.ResponseWriter, r *http.Request, deviceId int64) {
+	f := devices.ReservationFilter{DeviceId: deviceId}
+
+	var err error
+	f.From, err = parseTimeQuery(r, "from")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.To, err = parseTimeQuery(r, "to")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rr, err := s.reservations.Reservations(r.Context(), f)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, rr)
+}
+
+// CancelReservation swagger:route DELETE /devices/{id}/reservations/{reservationId} devices cancelReservation
+//
+// Cancels a reservation.
+//
+// Responses:
+//
+//	default: genericError
+//	    204:
+//	    404: genericError
+//	    500: internalServerError
+func (s *Server) CancelReservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rid, err := strconv.ParseInt(chi.URLParam(r, "reservationId"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.reservations.CancelReservation(r.Context(), id, rid)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,10 +1,49 @@
 package rest
 
+import (
+	"devices_api/internal/devices"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
 // TODO:
 // Request and Response payloads for the REST api.
 
 // TODO:
 // Request payload for Device data model.
 
-// TODO:
-// Response payload for the Device data model.
+// DeviceResponse is the response payload for the Device data model. Besides
+// the stored fields it lists the states the device can move to next.
+//
+// swagger:model device
+type DeviceResponse struct {
+	*devices.Device
+
+	AllowedTransitions []devices.DeviceState `json:"allowed_transitions"`
+}
+
+// DeviceRequest is the body of a device update. It accepts a device as the
+// API returns it; the allowed transitions sent back are ignored.
+type DeviceRequest struct {
+	devices.Device
+
+	AllowedTransitions []devices.DeviceState `json:"allowed_transitions,omitempty"`
+}
+
+func NewDeviceResponse(d *devices.Device) *DeviceResponse {
+	return &DeviceResponse{Device: d}
+}
+
+func (dr *DeviceResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	dr.AllowedTransitions = dr.Device.AllowedTransitions()
+	return nil
+}
+
+func NewDeviceListResponse(dd []devices.Device) []render.Renderer {
+	list := make([]render.Renderer, 0, len(dd))
+	for i := range dd {
+		list = append(list, NewDeviceResponse(&dd[i]))
+	}
+	return list
+}
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"devices_api/internal/devices"
 	"net/http"
 
 	"github.com/go-chi/render"
@@ -20,18 +21,31 @@ type GenericError struct {
 }
 
 // A ValidationError is an error that is generated for validation failures.
-// It has the same fields as a generic error but adds a Field property.
+// It has the same fields as a generic error but adds a Field property, the
+// first bad field, and Fields, which lists every bad field.
 //
 // swagger:response validationError
 type ValidationError struct {
 	// in: body
 	Body struct {
-		Code    int32  `json:"code"`
-		Message string `json:"message"`
-		Field   string `json:"field"`
+		Code    int32                `json:"code"`
+		Message string               `json:"message"`
+		Field   string               `json:"field"`
+		Fields  []devices.FieldError `json:"fields"`
 	} `json:"body"`
 }
 
+func NewValidationError(err *devices.ErrValidation) *ValidationError {
+	ve := &ValidationError{}
+	ve.Body.Code = http.StatusUnprocessableEntity
+	ve.Body.Message = err.Error()
+	ve.Body.Fields = err.Fields
+	if len(err.Fields) > 0 {
+		ve.Body.Field = err.Fields[0].Field
+	}
+	return ve
+}
+
 type ErrResponse struct {
 	Err            error `json:"-"` // low-level runtime error
 	HTTPStatusCode int   `json:"-"` // http response status code
@@ -2,11 +2,14 @@ package server
 
 import (
 	"devices_api/internal/devices"
+	"devices_api/internal/server/rest"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -25,6 +28,7 @@ func (s *Server) RegisterRoutes() http.Handler {
 	// REST api routes begin
 	// TODO: REST api router should be moved to the rest package
 	apiRouter := chi.NewRouter()
+	apiRouter.Use(actor)
 	r.Mount("/api/v1", apiRouter)
 
 	apiRouter.Post("/devices", s.CreateDevice)
@@ -33,13 +37,121 @@ func (s *Server) RegisterRoutes() http.Handler {
 
 	apiRouter.Get("/devices/{id}", s.DeviceById)
 
+	apiRouter.Get("/devices/by-tag/{tag}", s.DeviceByAssetTag)
+
 	apiRouter.Get("/devices/brand/{brand}", s.DevicesByBrand)
 
-	apiRouter.Get("devices/state/{state}", s.DevicesByState)
+	apiRouter.Get("/devices/state/{state}", s.DevicesByState)
+
+	apiRouter.Get("/devices", s.AllDevices)
 
 	apiRouter.Get("/devices/all", s.AllDevices)
 
-	apiRouter.Delete("devices/delete/", s.DeleteDevice)
+	apiRouter.Delete("/devices/{id}", s.DeleteDevice)
+
+	apiRouter.Delete("/devices/delete/", s.DeleteDevice)
+
+	if s.labels != nil {
+		apiRouter.Patch("/devices/{id}/labels", s.UpdateDeviceLabels)
+	}
+
+	if s.history != nil {
+		apiRouter.Get("/devices/{id}/history", s.DeviceHistory)
+	}
+
+	if s.leases != nil {
+		apiRouter.Post("/devices/{id}/checkout", s.CheckoutDevice)
+		apiRouter.Post("/devices/{id}/checkin", s.CheckinDevice)
+	}
+
+	if s.reservations != nil {
+		apiRouter.Post("/devices/{id}/reservations", s.ReserveDevice)
+		apiRouter.Get("/devices/{id}/reservations", s.DeviceReservations)
+		apiRouter.Delete("/devices/{id}/reservations/{reservationId}", s.CancelReservation)
+		apiRouter.Get("/reservations", s.AllReservations)
+	}
+
+	if s.maintenance != nil {
+		apiRouter.Post("/devices/{id}/maintenance", s.ScheduleMaintenance)
+		apiRouter.Get("/devices/{id}/maintenance", s.DeviceMaintenance)
+		apiRouter.Delete("/devices/{id}/maintenance/{windowId}", s.CancelMaintenance)
+		apiRouter.Get("/maintenance", s.AllMaintenance)
+	}
+
+	if s.trash != nil {
+		apiRouter.Get("/devices/trash", s.TrashedDevices)
+		apiRouter.Post("/devices/{id}/restore", s.RestoreDevice)
+	}
+
+	if s.brands != nil {
+		apiRouter.Post("/brands", s.CreateBrand)
+		apiRouter.Get("/brands", s.AllBrands)
+		apiRouter.Get("/brands/{id}", s.BrandById)
+		apiRouter.Put("/brands/{id}", s.RenameBrand)
+		apiRouter.Delete("/brands/{id}", s.DeleteBrand)
+		apiRouter.Post("/brands/{id}/aliases", s.AddBrandAlias)
+		apiRouter.Delete("/brands/{id}/aliases/{alias}", s.RemoveBrandAlias)
+	}
+
+	if s.models != nil {
+		apiRouter.Post("/brands/{id}/models", s.CreateModel)
+		apiRouter.Get("/brands/{id}/models", s.BrandModels)
+		apiRouter.Get("/models/inventory", s.ModelInventory)
+		apiRouter.Get("/models/{id}", s.ModelById)
+		apiRouter.Delete("/models/{id}", s.DeleteModel)
+		apiRouter.Get("/models/{id}/devices", s.ModelDevices)
+	}
+
+	if s.locations != nil {
+		apiRouter.Post("/locations", s.CreateLocation)
+		apiRouter.Get("/locations", s.AllLocations)
+		apiRouter.Get("/locations/{id}", s.LocationById)
+		apiRouter.Put("/locations/{id}", s.UpdateLocation)
+		apiRouter.Delete("/locations/{id}", s.DeleteLocation)
+		apiRouter.Get("/locations/{id}/devices", s.LocationDevices)
+		apiRouter.Post("/devices/{id}/move", s.MoveDevice)
+	}
+
+	if s.directory != nil {
+		apiRouter.Post("/users", s.CreateUser)
+		apiRouter.Get("/users", s.AllUsers)
+		apiRouter.Get("/users/{id}", s.UserById)
+		apiRouter.Post("/teams", s.CreateTeam)
+		apiRouter.Get("/teams", s.AllTeams)
+		apiRouter.Get("/teams/{id}", s.TeamById)
+		apiRouter.Get("/teams/{id}/members", s.TeamMembers)
+		apiRouter.Post("/teams/{id}/members", s.AddTeamMember)
+		apiRouter.Delete("/teams/{id}/members/{userId}", s.RemoveTeamMember)
+	}
+
+	if s.ownership != nil {
+		apiRouter.Put("/devices/{id}/assignee", s.AssignDevice)
+		apiRouter.Get("/users/{id}/devices", s.UserDevices)
+		apiRouter.Get("/teams/{id}/devices", s.TeamDevices)
+		apiRouter.Post("/devices/{id}/transfers", s.RequestTransfer)
+		apiRouter.Get("/devices/{id}/transfers", s.DeviceTransfers)
+		apiRouter.Get("/teams/{id}/transfers", s.TeamTransfers)
+		apiRouter.Post("/transfers/{id}/accept", s.resolveTransfer(devices.TransferAccepted))
+		apiRouter.Post("/transfers/{id}/reject", s.resolveTransfer(devices.TransferRejected))
+		apiRouter.Post("/transfers/{id}/cancel", s.resolveTransfer(devices.TransferCancelled))
+	}
+
+	if s.composition != nil {
+		apiRouter.Put("/devices/{id}/parent", s.SetDeviceParent)
+		apiRouter.Get("/devices/{id}/tree", s.DeviceTree)
+	}
+
+	if s.groups != nil {
+		apiRouter.Post("/groups", s.CreateGroup)
+		apiRouter.Get("/groups", s.AllGroups)
+		apiRouter.Get("/groups/{id}", s.GroupById)
+		apiRouter.Put("/groups/{id}", s.UpdateGroup)
+		apiRouter.Delete("/groups/{id}", s.DeleteGroup)
+		apiRouter.Post("/groups/{id}/members", s.AddGroupMembers)
+		apiRouter.Delete("/groups/{id}/members/{deviceId}", s.RemoveGroupMember)
+		apiRouter.Get("/groups/{id}/devices", s.GroupDevices)
+		apiRouter.Post("/groups/{id}/actions", s.ApplyToGroup)
+	}
 	// end of REST api routes
 
 	r.Get("/", s.HelloWorldHandler)
@@ -68,34 +180,39 @@ func (s *Server) HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 // Responses:
 //
 //		default: genericError
-//		200: device
+//		201: device
+//		400: genericError
+//		409: genericError
+//		422: validationError
 //	 	500: internalServerError
 func (s *Server) CreateDevice(w http.ResponseWriter, r *http.Request) {
 	var device devices.CreateDevice
 
-	json.NewDecoder(r.Body).Decode(&device)
+	err := devices.Decode(r.Body, &device)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
 
-	d, err := s.db.Create(r.Context(), device)
+	d, err := s.devices.Create(r.Context(), device)
 	if err != nil {
-		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, statusFor(err))
 		return
 	}
-	//defer s.db.Close()
 
 	render.Status(r, http.StatusCreated)
-	json.NewEncoder(w).Encode(d)
-	// render.Render(w, r, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
 }
 
 // DeviceById swagger:route GET /devices/{id}
 //
-// Get a device by its ID.
+// Get a device by its ID. The device version is returned in the ETag header.
 //
 // Responses:
 //
 //		default: genericError
 //		200: device
+//		404: genericError
 //	 	500: internalServerError
 func (s *Server) DeviceById(w http.ResponseWriter, r *http.Request) {
 	idUrl := chi.URLParam(r, "id")
@@ -107,33 +224,78 @@ func (s *Server) DeviceById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	d, err := s.db.GetById(r.Context(), id)
+	d, err := s.devices.GetById(r.Context(), id)
 	if err != nil {
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), statusFor(err))
 		return
 	}
 
-	json.NewEncoder(w).Encode(d)
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
 }
 
-// AllDevices swagger:route GET /devices/all
+// DeviceByAssetTag swagger:route GET /devices/by-tag/{tag}
 //
-// Get all devices.
+// Get a device by the asset tag on its label, as read by a barcode scanner.
+// The device version is returned in the ETag header.
+//
+// Responses:
+//
+//		default: genericError
+//		200: device
+//		404: genericError
+//	 	500: internalServerError
+func (s *Server) DeviceByAssetTag(w http.ResponseWriter, r *http.Request) {
+	d, err := s.devices.GetByAssetTag(r.Context(), chi.URLParam(r, "tag"))
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
+}
+
+// AllDevices swagger:route GET /devices devices allDevices
+//
+// Get all devices. Custom attributes can be filtered with attr.<key><op><value>
+// query parameters, where op is one of =, !=, >, >=, < or <=, for example
+// ?attr.os=android&attr.ram_gb>=8. Labels can be filtered with a selector
+// such as ?selector=team=qa,env!=prod,tier in (web,db),!gpu. Lifecycle
+// dates can be filtered with ?warranty_expires_before=2027-01-01 and
+// ?end_of_life_before=2027-01-01.
 //
 // Responses:
 //
 //		default: genericError
 //		200: []device
+//		400: genericError
 //	 	500: internalServerError
 func (s *Server) AllDevices(w http.ResponseWriter, r *http.Request) {
-	dd, err := s.db.All(r.Context())
+	f, err := parseDeviceFilter(r)
 	if err != nil {
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	json.NewEncoder(w).Encode(dd)
+
+	var dd []devices.Device
+	switch {
+	case f.IsEmpty():
+		dd, err = s.devices.All(r.Context())
+	case s.finder != nil:
+		dd, err = s.finder.Find(r.Context(), f)
+	default:
+		err = errFilterUnsupported
+	}
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
 }
 
 // DevicesByBrand swagger:route GET /devices/brand/{brand}
@@ -148,101 +310,286 @@ func (s *Server) AllDevices(w http.ResponseWriter, r *http.Request) {
 func (s *Server) DevicesByBrand(w http.ResponseWriter, r *http.Request) {
 	brand := chi.URLParam(r, "brand")
 
-	dd, err := s.db.GetByBrand(r.Context(), brand)
+	dd, err := s.devices.GetByBrand(r.Context(), brand)
 	if err != nil {
 		log.Println(w, r, err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(dd)
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
 }
 
 // UpdateDevcie swagger:route PUT /devices/{id} devices updateDevice
 //
-// Updates the parameters for a device.
+// Updates the parameters for a device. State changes must follow the device
+// state machine. When an If-Match header is sent, the update only succeeds
+// if it matches the current device ETag.
 //
 // Responses:
 //
 //	default: genericError
 //	    200: device
+//	    400: genericError
+//	    404: genericError
+//	    409: genericError
+//	    412: genericError
+//	    422: validationError
 //	    500: internalServerError
 func (s *Server) UpdateDevice(w http.ResponseWriter, r *http.Request) {
-	var device devices.Device
-	json.NewDecoder(r.Body).Decode(&device)
-
-	d, err := s.db.GetById(r.Context(), device.Id)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	du, err := s.db.Update(r.Context(), *d)
+	version, err := ifMatchVersion(r)
 	if err != nil {
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req rest.DeviceRequest
+	err = devices.Decode(r.Body, &req)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
-	json.NewEncoder(w).Encode(du)
+	req.Device.Id = id
+
+	d, err := s.devices.Update(r.Context(), req.Device, version)
+	if err != nil {
+		writeError(w, r, err, statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
 }
 
-// DevicesByState swagger:route PUT /devices/state/{state} devices DevicesByState
+// DevicesByState swagger:route GET /devices/state/{state} devices DevicesByState
 //
-// Get devices in the parameter state.
+// Get devices in the parameter state, given by name such as in_use. The
+// numbers of the states are still accepted.
 //
 // Responses:
 //
 //	default: genericError
 //	    200: []device
+//	    400: genericError
 //	    500: internalServerError
 func (s *Server) DevicesByState(w http.ResponseWriter, r *http.Request) {
-	state := chi.URLParam(r, "state")
-
-	st, err := strconv.ParseInt(state, 10, 64)
+	ds, err := devices.ParseDeviceState(chi.URLParam(r, "state"))
 	if err != nil {
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), statusFor(err))
 		return
 	}
 
-	dd, err := s.db.GetByState(r.Context(), devices.DeviceState(st))
+	dd, err := s.devices.GetByState(r.Context(), ds)
 	if err != nil {
 		log.Println(w, r, err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(dd)
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
 }
 
 // DeleteDevice swagger:route DELETE /devices/{id} device deleteDevice
 //
-// Deletes a device.
+// Moves a device to the trash, from where it can be restored until it is
+// purged. When an If-Match header is sent, the device is only deleted if it
+// matches the current device ETag. Devices in use cannot be deleted. The
+// legacy DELETE /devices/delete/ route takes the id from the request body
+// instead; only the id is read from it.
 //
 // Responses:
 //
 //		default: genericError
 //		204:
+//		404: genericError
+//		409: genericError
+//		412: genericError
 //	    500: internalServerError
 func (s *Server) DeleteDevice(w http.ResponseWriter, r *http.Request) {
-	var device devices.Device
-	json.NewDecoder(r.Body).Decode(&device)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	result, err := s.db.Delete(r.Context(), device)
+	var id int64
+	if idUrl := chi.URLParam(r, "id"); idUrl != "" {
+		id, err = strconv.ParseInt(idUrl, 10, 64)
+	} else {
+		var device devices.Device
+		err = json.NewDecoder(r.Body).Decode(&device)
+		id = device.Id
+	}
 	if err != nil {
-		if errors.Is(err, devices.ErrDeviceInUse) {
-			log.Println(w, r, err.Error())
-			http.Error(w, err.Error(), http.StatusAccepted)
-			return
-		}
 		log.Println(w, r, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.devices.Delete(r.Context(), id, version)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeviceHistory swagger:route GET /devices/{id}/history devices deviceHistory
+//
+// Get the change history of a device. The optional from and to query
+// parameters (RFC 3339) restrict the result to changes made in [from, to).
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []historyEntry
+//	    400: genericError
+//	    500: internalServerError
+func (s *Server) DeviceHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	i, err := result.RowsAffected()
-	json.NewEncoder(w).Encode(i)
+
+	var f devices.HistoryFilter
+
+	f.From, err = parseTimeQuery(r, "from")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.To, err = parseTimeQuery(r, "to")
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hh, err := s.history.History(r.Context(), id, f)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	render.JSON(w, r, hh)
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter. A missing
+// parameter yields the zero time.
+func parseTimeQuery(r *http.Request, key string) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return t, nil
+}
+
+var errFilterUnsupported = fmt.Errorf("%w: filtering is not supported by this repository", devices.ErrInvalidFilter)
+
+// writeError logs err and writes it to the client. Validation failures are
+// answered with 422 and every bad field, anything else with status.
+func writeError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	log.Println(w, r, err.Error())
+
+	var validation *devices.ErrValidation
+	if errors.As(err, &validation) {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, rest.NewValidationError(validation).Body)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// statusFor maps domain and repository errors to the HTTP status code the
+// handlers answer with.
+func statusFor(err error) int {
+	var (
+		invalidTransition *devices.ErrInvalidTransition
+		versionConflict   *devices.ErrVersionConflict
+		validation        *devices.ErrValidation
+	)
+
+	switch {
+	case errors.As(err, &validation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, devices.ErrNotExist):
+		return http.StatusNotFound
+	case errors.As(err, &versionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, devices.ErrWrongTransferTeam),
+		errors.Is(err, devices.ErrNotTeamMember):
+		return http.StatusForbidden
+	case errors.As(err, &invalidTransition),
+		errors.Is(err, devices.ErrDeviceCheckedOut),
+		errors.Is(err, devices.ErrNotCheckedOut),
+		errors.Is(err, devices.ErrReservationConflict),
+		errors.Is(err, devices.ErrDeviceReserved),
+		errors.Is(err, devices.ErrDuplicate),
+		errors.Is(err, devices.ErrBrandInUse),
+		errors.Is(err, devices.ErrModelInUse),
+		errors.Is(err, devices.ErrLocationInUse),
+		errors.Is(err, devices.ErrTransferPending),
+		errors.Is(err, devices.ErrTransferResolved),
+		errors.Is(err, devices.ErrMaintenanceConflict),
+		errors.Is(err, devices.ErrMaintenanceStarted),
+		errors.Is(err, devices.ErrDeviceInMaintenance),
+		errors.Is(err, devices.ErrGroupActionFailed),
+		errors.Is(err, devices.ErrCompositionCycle),
+		errors.Is(err, devices.ErrParentInUse),
+		errors.Is(err, devices.ErrDeviceInUse),
+		errors.Is(err, devices.ErrDeviceInUseChange),
+		errors.Is(err, devices.ErrConcurrentUpdate),
+		errors.Is(err, devices.ErrAssemblyInUse),
+		errors.Is(err, devices.ErrDeviceHasParts):
+		return http.StatusConflict
+	case errors.Is(err, devices.ErrInvalidState),
+		errors.Is(err, devices.ErrInvalidCheckout),
+		errors.Is(err, devices.ErrInvalidReservation),
+		errors.Is(err, devices.ErrInvalidFilter),
+		errors.Is(err, devices.ErrInvalidLabel),
+		errors.Is(err, devices.ErrUnknownBrand),
+		errors.Is(err, devices.ErrInvalidBrand),
+		errors.Is(err, devices.ErrUnknownModel),
+		errors.Is(err, devices.ErrInvalidModel),
+		errors.Is(err, devices.ErrInvalidLocation),
+		errors.Is(err, devices.ErrUnknownLocation),
+		errors.Is(err, devices.ErrInvalidUser),
+		errors.Is(err, devices.ErrInvalidTeam),
+		errors.Is(err, devices.ErrUnknownUser),
+		errors.Is(err, devices.ErrUnknownTeam),
+		errors.Is(err, devices.ErrInvalidTransfer),
+		errors.Is(err, devices.ErrInvalidMaintenance),
+		errors.Is(err, devices.ErrInvalidLifecycle),
+		errors.Is(err, devices.ErrInvalidGroup),
+		errors.Is(err, devices.ErrInvalidGroupAction),
+		errors.Is(err, devices.ErrUnknownParent):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	jsonResp, _ := json.Marshal(s.db.Health())
+	jsonResp, _ := json.Marshal(s.devices.Health())
 	_, _ = w.Write(jsonResp)
 }
@@ -1,16 +1,24 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"devices_api/internal/devices"
+	"devices_api/internal/devices/memory"
 	"devices_api/mock"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/mock/gomock"
 )
 
@@ -55,7 +63,7 @@ func TestCreateDevice(t *testing.T) {
 	mockRepo := mock.NewMockRepository(ctrl)
 
 	mockRepo.EXPECT().
-		Create(context.Background(), devices.CreateDevice{}).
+		Create(context.Background(), devices.CreateDevice{Name: "Device1", Brand: "Brand1"}).
 		Return(&devices.Device{Id: 1,
 			Name:      "Device1",
 			Brand:     "Brand1",
@@ -64,8 +72,9 @@ func TestCreateDevice(t *testing.T) {
 			nil)
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/devices", nil)
-	s.db = mockRepo
+	in := strings.NewReader(`{"name":"Device1","brand":"Brand1"}`)
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/devices", in)
+	s.devices = devices.NewService(mockRepo)
 	s.CreateDevice(w, r)
 
 	resp := w.Result()
@@ -76,10 +85,1195 @@ func TestCreateDevice(t *testing.T) {
 		t.Errorf("io.ReadAll() error = %s; want nil", err)
 	}
 
-	want := "{\"id\":1,\"name\":\"Device1\",\"brand\":\"Brand1\",\"state\":1,\"created_at\":\"2009-11-10T23:00:00Z\" }"
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status Created; got %v", resp.Status)
+	}
+
+	want := "{\"id\":1,\"name\":\"Device1\",\"brand\":\"Brand1\",\"state\":\"in_use\",\"created_at\":\"2009-11-10T23:01:02Z\",\"version\":0,\"allowed_transitions\":[\"available\"]}\n"
 
 	if string(body) != want {
 		t.Errorf("got %v ; want %v", string(body), want)
 	}
 
 }
+
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestUpdateDevice_InvalidTransition(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	// The service refuses the transition before anything is written.
+	mockRepo.EXPECT().
+		GetById(gomock.Any(), int64(1)).
+		Return(&devices.Device{Id: 1, Name: "Device1", Brand: "Brand1", BrandId: 2, State: devices.Inactive}, nil)
+
+	body := strings.NewReader(`{"name":"Device1","brand":"Brand1","state":1}`)
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/devices/1", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.UpdateDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestUpdateDevice_RenameInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	mockRepo.EXPECT().
+		GetById(gomock.Any(), int64(1)).
+		Return(&devices.Device{Id: 1, Name: "Device1", Brand: "Brand1", BrandId: 2, State: devices.InUse}, nil)
+
+	body := strings.NewReader(`{"name":"Renamed","brand":"Brand1","state":"in_use"}`)
+	r := httptest.NewRequestWithContext(context.Background(), http.MethodPut, "/devices/1", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.UpdateDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestUpdateDevice_IfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	device := devices.Device{Id: 1, Name: "Device1", Brand: "Brand1", BrandId: 2, State: devices.Available, Version: 3}
+	updated := device
+	updated.Version = 4
+
+	gomock.InOrder(
+		mockRepo.EXPECT().
+			GetById(gomock.Any(), int64(1)).
+			Return(&device, nil),
+		mockRepo.EXPECT().
+			Update(gomock.Any(), device, int64(3)).
+			Return(nil, nil),
+		mockRepo.EXPECT().
+			GetById(gomock.Any(), int64(1)).
+			Return(&updated, nil),
+		mockRepo.EXPECT().
+			GetById(gomock.Any(), int64(1)).
+			Return(&updated, nil),
+	)
+
+	for _, want := range []int{http.StatusOK, http.StatusPreconditionFailed} {
+		body := strings.NewReader(`{"name":"Device1","brand":"Brand1","state":0}`)
+		r := httptest.NewRequest(http.MethodPut, "/devices/1", body)
+		r.Header.Set("If-Match", `"3"`)
+		r = withURLParam(r, "id", "1")
+		w := httptest.NewRecorder()
+
+		s.UpdateDevice(w, r)
+
+		if w.Code != want {
+			t.Errorf("expected status %v; got %v", want, w.Code)
+		}
+		if want == http.StatusOK && w.Header().Get("ETag") != `"4"` {
+			t.Errorf("expected ETag \"4\"; got %v", w.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int64
+		wantErr bool
+	}{
+		{"", devices.AnyVersion, false},
+		{"*", devices.AnyVersion, false},
+		{`"7"`, 7, false},
+		{"7", 0, true},
+		{`"0"`, 0, true},
+		{`W/"7"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/devices/1", nil)
+		r.Header.Set("If-Match", tt.header)
+
+		got, err := ifMatchVersion(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ifMatchVersion(%q) error = %v; wantErr %v", tt.header, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ifMatchVersion(%q) = %d; want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHistory := mock.NewMockHistoryReader(ctrl)
+	s := &Server{history: mockHistory}
+
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	mockHistory.EXPECT().
+		History(gomock.Any(), int64(1), devices.HistoryFilter{From: from}).
+		Return([]devices.HistoryEntry{{
+			Id:        7,
+			DeviceId:  1,
+			Action:    devices.HistoryUpdated,
+			Actor:     "alice",
+			Before:    &devices.Device{Id: 1, State: devices.InUse},
+			After:     &devices.Device{Id: 1, State: devices.Available},
+			ChangedAt: changedAt,
+		}}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/history?from=2024-01-01T00:00:00Z", nil)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.DeviceHistory(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", w.Code)
+	}
+
+	want := `[{"id":7,"device_id":1,"action":"update","actor":"alice",` +
+		`"before":{"id":1,"name":"","brand":"","state":"in_use","created_at":"0001-01-01T00:00:00Z","version":0},` +
+		`"after":{"id":1,"name":"","brand":"","state":"available","created_at":"0001-01-01T00:00:00Z","version":0},` +
+		`"changed_at":"2024-01-02T10:00:00Z"}]` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("got %v ; want %v", w.Body.String(), want)
+	}
+}
+
+func TestDeviceHistory_InvalidRange(t *testing.T) {
+	s := &Server{}
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/history?to=yesterday", nil)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.DeviceHistory(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request; got %v", w.Code)
+	}
+}
+
+func TestCheckoutDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLeases := mock.NewMockLeaseRepository(ctrl)
+	s := &Server{leases: mockLeases}
+
+	until := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	mockLeases.EXPECT().
+		Checkout(gomock.Any(), int64(1), devices.Checkout{Holder: "alice", ExpectedReturnAt: until}).
+		Return(nil, devices.ErrDeviceCheckedOut)
+
+	body := strings.NewReader(`{"holder":"alice","expected_return_at":"2030-01-01T00:00:00Z"}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices/1/checkout", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.CheckoutDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestReapExpiredLeases(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLeases := mock.NewMockLeaseRepository(ctrl)
+	s := &Server{leases: mockLeases}
+
+	mockLeases.EXPECT().
+		ExpireLeases(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, now time.Time) ([]devices.Lease, error) {
+			if got := devices.ActorFromContext(ctx); got != leaseReaperActor {
+				t.Errorf("got actor %q; want %q", got, leaseReaperActor)
+			}
+			return []devices.Lease{{Id: 1, DeviceId: 2, Holder: "alice"}}, nil
+		})
+
+	s.reapExpiredLeases(context.Background())
+}
+
+func TestReapExpiredLeases_PartialFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLeases := mock.NewMockLeaseRepository(ctrl)
+	s := &Server{leases: mockLeases}
+
+	mockLeases.EXPECT().
+		ExpireLeases(gomock.Any(), gomock.Any()).
+		Return([]devices.Lease{{Id: 1, DeviceId: 2, Holder: "alice"}}, errors.New("lease 3 of device 4: boom"))
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	s.reapExpiredLeases(context.Background())
+
+	if !strings.Contains(buf.String(), "lease 3 of device 4: boom") {
+		t.Errorf("expected the failure to be logged; got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "lease 1 of device 2") {
+		t.Errorf("expected the expired lease to be logged; got %q", buf.String())
+	}
+}
+
+func TestReserveDevice_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReservations := mock.NewMockReservationRepository(ctrl)
+	s := &Server{reservations: mockReservations}
+
+	mockReservations.EXPECT().
+		Reserve(gomock.Any(), int64(3), gomock.Any()).
+		Return(nil, devices.ErrReservationConflict)
+
+	body := strings.NewReader(`{"holder":"qa","starts_at":"2030-01-01T09:00:00Z","ends_at":"2030-01-01T17:00:00Z"}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices/3/reservations", body)
+	r = withURLParam(r, "id", "3")
+	w := httptest.NewRecorder()
+
+	s.ReserveDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestAllReservations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockReservations := mock.NewMockReservationRepository(ctrl)
+	s := &Server{reservations: mockReservations}
+
+	from := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2030, time.January, 8, 0, 0, 0, 0, time.UTC)
+
+	mockReservations.EXPECT().
+		Reservations(gomock.Any(), devices.ReservationFilter{From: from, To: to}).
+		Return([]devices.Reservation{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/reservations?from=2030-01-01T00:00:00Z&to=2030-01-08T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	s.AllReservations(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+	if w.Body.String() != "[]\n" {
+		t.Errorf("got %v ; want []", w.Body.String())
+	}
+}
+
+func TestRestoreDevice_NotInTrash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTrash := mock.NewMockTrashRepository(ctrl)
+	s := &Server{trash: mockTrash}
+
+	mockTrash.EXPECT().
+		Restore(gomock.Any(), int64(5)).
+		Return(nil, devices.ErrNotExist)
+
+	r := httptest.NewRequest(http.MethodPost, "/devices/5/restore", nil)
+	r = withURLParam(r, "id", "5")
+	w := httptest.NewRecorder()
+
+	s.RestoreDevice(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status Not Found; got %v", w.Code)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTrash := mock.NewMockTrashRepository(ctrl)
+	s := &Server{trash: mockTrash}
+
+	retention := 48 * time.Hour
+	start := time.Now()
+
+	mockTrash.EXPECT().
+		Purge(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+			if deletedBefore.Before(start.Add(-retention)) || deletedBefore.After(time.Now().Add(-retention)) {
+				t.Errorf("purge cutoff %v is not %s ago", deletedBefore, retention)
+			}
+			return 2, nil
+		})
+
+	s.purgeTrash(retention)(context.Background())
+}
+
+func TestAllDevices_AttributeFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFinder := mock.NewMockFinder(ctrl)
+	s := &Server{finder: mockFinder}
+
+	mockFinder.EXPECT().
+		Find(gomock.Any(), devices.DeviceFilter{Attributes: []devices.AttributeFilter{
+			{Key: "os", Op: devices.AttrEq, Value: "android"},
+			{Key: "ram_gb", Op: devices.AttrGe, Value: 8.0},
+		}}).
+		Return([]devices.Device{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?attr.os=android&attr.ram_gb%3E=8", nil)
+	w := httptest.NewRecorder()
+
+	s.AllDevices(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+}
+
+func TestAllDevices_InvalidFilter(t *testing.T) {
+	s := &Server{}
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?attr.ram_gb>=lots", nil)
+	w := httptest.NewRecorder()
+
+	s.AllDevices(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request; got %v", w.Code)
+	}
+}
+
+func TestAllDevices_Selector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFinder := mock.NewMockFinder(ctrl)
+	s := &Server{finder: mockFinder}
+
+	mockFinder.EXPECT().
+		Find(gomock.Any(), devices.DeviceFilter{Labels: devices.Selector{
+			{Key: "team", Op: devices.SelectEquals, Values: []string{"qa"}},
+			{Key: "env", Op: devices.SelectNotEquals, Values: []string{"prod"}},
+		}}).
+		Return([]devices.Device{{Id: 1, Labels: map[string]string{"team": "qa"}}}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?selector=team%3Dqa%2Cenv!%3Dprod", nil)
+	w := httptest.NewRecorder()
+
+	s.AllDevices(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+}
+
+func TestUpdateDeviceLabels(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLabels := mock.NewMockLabelRepository(ctrl)
+	s := &Server{labels: mockLabels}
+
+	mockLabels.EXPECT().
+		UpdateLabels(gomock.Any(), int64(1), map[string]string{"team": "qa"}, []string{"env"}).
+		Return(map[string]string{"team": "qa", "rack": "r12"}, nil)
+
+	body := strings.NewReader(`{"team":"qa","env":null}`)
+	r := httptest.NewRequest(http.MethodPatch, "/devices/1/labels", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.UpdateDeviceLabels(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+	if want := `{"rack":"r12","team":"qa"}` + "\n"; w.Body.String() != want {
+		t.Errorf("got %v ; want %v", w.Body.String(), want)
+	}
+}
+
+func TestUpdateDeviceLabels_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLabels := mock.NewMockLabelRepository(ctrl)
+	s := &Server{labels: mockLabels}
+
+	body := strings.NewReader(`{"team":7,"-env":"prod"}`)
+	r := httptest.NewRequest(http.MethodPatch, "/devices/1/labels", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.UpdateDeviceLabels(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status Unprocessable Entity; got %v", w.Code)
+	}
+	for _, field := range []string{`"field":"team"`, `"field":"-env"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("expected %s to be listed; got %s", field, w.Body.String())
+		}
+	}
+}
+
+func TestCreateUser_UnknownFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDirectory := mock.NewMockDirectoryRepository(ctrl)
+	s := &Server{directory: mockDirectory}
+
+	body := strings.NewReader(`{"name":"bob","mail":"bob@example.com"}`)
+	r := httptest.NewRequest(http.MethodPost, "/users", body)
+	w := httptest.NewRecorder()
+
+	s.CreateUser(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status Unprocessable Entity; got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"field":"mail"`) {
+		t.Errorf("expected mail to be listed; got %s", w.Body.String())
+	}
+}
+
+func TestRenameBrand_Duplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBrands := mock.NewMockBrandRepository(ctrl)
+	s := &Server{brands: mockBrands}
+
+	mockBrands.EXPECT().
+		RenameBrand(gomock.Any(), int64(1), "Apple").
+		Return(nil, devices.ErrDuplicate)
+
+	r := httptest.NewRequest(http.MethodPut, "/brands/1", strings.NewReader(`{"name":"Apple"}`))
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.RenameBrand(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestModelInventory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockModels := mock.NewMockModelRepository(ctrl)
+	s := &Server{models: mockModels}
+
+	mockModels.EXPECT().
+		Inventory(gomock.Any(), int64(2)).
+		Return([]devices.ModelInventory{{
+			ModelId: 1, Model: "Pixel 8", BrandId: 2, Brand: "Google", Total: 3,
+			ByState: map[devices.DeviceState]int64{devices.Available: 2, devices.InUse: 1},
+		}}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/models/inventory?brand_id=2", nil)
+	w := httptest.NewRecorder()
+
+	s.ModelInventory(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", w.Code)
+	}
+
+	want := `[{"model_id":1,"model":"Pixel 8","brand_id":2,"brand":"Google","total":3,"by_state":{"available":2,"in_use":1}}]` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("expected response body to be %v; got %v", want, w.Body.String())
+	}
+}
+
+func TestCreateDevice_DuplicateSerialNumber(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	mockRepo.EXPECT().
+		Create(gomock.Any(), devices.CreateDevice{Name: "Device1", Brand: "Brand1", SerialNumber: "SN-1"}).
+		Return(nil, &devices.ErrDuplicateField{Field: "serial_number", Value: "SN-1"})
+
+	body := strings.NewReader(`{"name":"Device1","brand":"Brand1","serial_number":"SN-1"}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices", body)
+	w := httptest.NewRecorder()
+
+	s.CreateDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "serial_number") {
+		t.Errorf("expected the conflicting field in the response; got %v", w.Body.String())
+	}
+}
+
+func TestDeviceByAssetTag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	mockRepo.EXPECT().
+		GetByAssetTag(gomock.Any(), "LAB-0042").
+		Return(&devices.Device{Id: 7, Name: "Device7", AssetTag: "LAB-0042", Version: 2}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/by-tag/LAB-0042", nil)
+	r = withURLParam(r, "tag", "LAB-0042")
+	w := httptest.NewRecorder()
+
+	s.DeviceByAssetTag(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", w.Code)
+	}
+	if w.Header().Get("ETag") != `"2"` {
+		t.Errorf("expected ETag \"2\"; got %v", w.Header().Get("ETag"))
+	}
+}
+
+func TestMoveDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLocations := mock.NewMockLocationRepository(ctrl)
+	s := &Server{locations: mockLocations}
+
+	mockLocations.EXPECT().
+		MoveDevice(gomock.Any(), int64(1), devices.Move{LocationId: 5}, int64(3)).
+		Return(&devices.Device{Id: 1, LocationId: 5, Version: 4}, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/devices/1/move", strings.NewReader(`{"location_id":5}`))
+	r.Header.Set("If-Match", `"3"`)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.MoveDevice(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", w.Code)
+	}
+	if w.Header().Get("ETag") != `"4"` {
+		t.Errorf("expected ETag \"4\"; got %v", w.Header().Get("ETag"))
+	}
+}
+
+func TestAcceptTransfer_AlreadyResolved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	mockOwnership.EXPECT().
+		ResolveTransfer(gomock.Any(), int64(4), devices.TransferResolution{TeamId: 3}, devices.TransferAccepted).
+		Return(nil, devices.ErrTransferResolved)
+
+	r := httptest.NewRequest(http.MethodPost, "/transfers/4/accept", strings.NewReader(`{"team_id":3}`))
+	r = withURLParam(r, "id", "4")
+	w := httptest.NewRecorder()
+
+	s.resolveTransfer(devices.TransferAccepted)(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestCancelTransfer_WrongTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	mockOwnership.EXPECT().
+		ResolveTransfer(gomock.Any(), int64(4), devices.TransferResolution{TeamId: 3}, devices.TransferCancelled).
+		Return(nil, devices.ErrWrongTransferTeam)
+
+	r := httptest.NewRequest(http.MethodPost, "/transfers/4/cancel", strings.NewReader(`{"team_id":3}`))
+	r = withURLParam(r, "id", "4")
+	w := httptest.NewRecorder()
+
+	s.resolveTransfer(devices.TransferCancelled)(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", w.Code)
+	}
+}
+
+func TestAcceptTransfer_NotMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	mockOwnership.EXPECT().
+		ResolveTransfer(gomock.Any(), int64(4), devices.TransferResolution{TeamId: 3}, devices.TransferAccepted).
+		Return(nil, devices.ErrNotTeamMember)
+
+	r := httptest.NewRequest(http.MethodPost, "/transfers/4/accept", strings.NewReader(`{"team_id":3}`))
+	r = withURLParam(r, "id", "4")
+	w := httptest.NewRecorder()
+
+	s.resolveTransfer(devices.TransferAccepted)(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", w.Code)
+	}
+}
+
+func TestAcceptTransfer_ZeroTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	r := httptest.NewRequest(http.MethodPost, "/transfers/4/accept", strings.NewReader(`{"team_id":0}`))
+	r = withURLParam(r, "id", "4")
+	w := httptest.NewRecorder()
+
+	s.resolveTransfer(devices.TransferAccepted)(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status UnprocessableEntity; got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"field":"team_id"`) {
+		t.Errorf("expected team_id to be reported; got %s", w.Body.String())
+	}
+}
+
+func TestActor_User(t *testing.T) {
+	var got int64
+	h := actor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = devices.UserFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-Id", "7")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got != 7 {
+		t.Errorf("expected user 7; got %v", got)
+	}
+
+	r.Header.Set("X-User-Id", "seven")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status BadRequest; got %v", w.Code)
+	}
+}
+
+func TestAcceptTransfer_NoTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	r := httptest.NewRequest(http.MethodPost, "/transfers/4/accept", nil)
+	r = withURLParam(r, "id", "4")
+	w := httptest.NewRecorder()
+
+	s.resolveTransfer(devices.TransferAccepted)(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status BadRequest; got %v", w.Code)
+	}
+}
+
+func TestTeamTransfers_Pending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOwnership := mock.NewMockOwnershipRepository(ctrl)
+	s := &Server{ownership: mockOwnership}
+
+	mockOwnership.EXPECT().
+		Transfers(gomock.Any(), devices.TransferFilter{TeamId: 3, Status: devices.TransferPending}).
+		Return([]devices.Transfer{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/teams/3/transfers?status=pending", nil)
+	r = withURLParam(r, "id", "3")
+	w := httptest.NewRecorder()
+
+	s.TeamTransfers(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+}
+
+func TestApplyMaintenance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMaintenance := mock.NewMockMaintenanceRepository(ctrl)
+	s := &Server{maintenance: mockMaintenance}
+
+	mockMaintenance.EXPECT().
+		ApplyMaintenance(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, now time.Time) ([]devices.MaintenanceWindow, error) {
+			if got := devices.ActorFromContext(ctx); got != maintenanceActor {
+				t.Errorf("got actor %q; want %q", got, maintenanceActor)
+			}
+			return []devices.MaintenanceWindow{{Id: 1, DeviceId: 2, Reason: "battery", Technician: "bob"}}, nil
+		})
+
+	s.applyMaintenance(context.Background())
+}
+
+func TestApplyMaintenance_PartialFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMaintenance := mock.NewMockMaintenanceRepository(ctrl)
+	s := &Server{maintenance: mockMaintenance}
+
+	ends := time.Now()
+	mockMaintenance.EXPECT().
+		ApplyMaintenance(gomock.Any(), gomock.Any()).
+		Return([]devices.MaintenanceWindow{{Id: 1, DeviceId: 2, EndedAt: &ends}}, errors.New("window 3 of device 4: boom"))
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	s.applyMaintenance(context.Background())
+
+	if !strings.Contains(buf.String(), "window 3 of device 4: boom") {
+		t.Errorf("expected the failure to be logged; got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "window 1 of device 2 ended") {
+		t.Errorf("expected the ended window to be logged; got %q", buf.String())
+	}
+}
+
+func TestCheckoutDevice_Maintenance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLeases := mock.NewMockLeaseRepository(ctrl)
+	s := &Server{leases: mockLeases}
+
+	mockLeases.EXPECT().
+		Checkout(gomock.Any(), int64(1), gomock.Any()).
+		Return(nil, devices.ErrDeviceInMaintenance)
+
+	body := strings.NewReader(`{"holder":"alice","expected_return_at":"2030-01-01T00:00:00Z"}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices/1/checkout", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.CheckoutDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestAllDevices_WarrantyExpiresBefore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFinder := mock.NewMockFinder(ctrl)
+	s := &Server{finder: mockFinder}
+
+	limit, _ := devices.ParseDate("2027-01-01")
+	mockFinder.EXPECT().
+		Find(gomock.Any(), devices.DeviceFilter{WarrantyExpiresBefore: &limit}).
+		Return([]devices.Device{}, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?warranty_expires_before=2027-01-01", nil)
+	w := httptest.NewRecorder()
+
+	s.AllDevices(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+}
+
+func TestAllDevices_InvalidDate(t *testing.T) {
+	s := &Server{}
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?end_of_life_before=soon", nil)
+	w := httptest.NewRecorder()
+
+	s.AllDevices(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request; got %v", w.Code)
+	}
+}
+
+func TestApplyToGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGroups := mock.NewMockGroupRepository(ctrl)
+	s := &Server{groups: mockGroups}
+
+	inactive := devices.Inactive
+	report := &devices.GroupReport{GroupId: 1, Applied: 1, Failed: 1, Results: []devices.GroupResult{
+		{DeviceId: 1, Applied: true, Device: &devices.Device{Id: 1, State: devices.Inactive}},
+		{DeviceId: 2, Error: devices.ErrDeviceInUse.Error()},
+	}}
+	mockGroups.EXPECT().
+		ApplyToGroup(gomock.Any(), int64(1), devices.GroupAction{State: &inactive}).
+		Return(report, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/groups/1/actions", strings.NewReader(`{"state":2}`))
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.ApplyToGroup(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+
+	var got devices.GroupReport
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Failed != 1 || len(got.Results) != 2 || got.Results[1].Error == "" {
+		t.Errorf("expected a report with one failed member; got %+v", got)
+	}
+}
+
+func TestApplyToGroup_AtomicFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGroups := mock.NewMockGroupRepository(ctrl)
+	s := &Server{groups: mockGroups}
+
+	report := &devices.GroupReport{GroupId: 1, Failed: 1, RolledBack: true, Results: []devices.GroupResult{
+		{DeviceId: 2, Error: devices.ErrDeviceInUse.Error()},
+	}}
+	mockGroups.EXPECT().
+		ApplyToGroup(gomock.Any(), int64(1), gomock.Any()).
+		Return(report, devices.ErrGroupActionFailed)
+
+	body := strings.NewReader(`{"set_labels":{"pool":"ci"},"atomic":true}`)
+	r := httptest.NewRequest(http.MethodPost, "/groups/1/actions", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.ApplyToGroup(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"rolled_back":true`) {
+		t.Errorf("expected the report in the body; got %s", w.Body.String())
+	}
+}
+
+func TestSetDeviceParent_Cycle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockComposition := mock.NewMockCompositionRepository(ctrl)
+	s := &Server{composition: mockComposition}
+
+	mockComposition.EXPECT().
+		SetParent(gomock.Any(), int64(1), devices.Attach{ParentId: 4}, devices.AnyVersion).
+		Return(nil, devices.ErrCompositionCycle)
+
+	r := httptest.NewRequest(http.MethodPut, "/devices/1/parent", strings.NewReader(`{"parent_id":4}`))
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.SetDeviceParent(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", w.Code)
+	}
+}
+
+func TestDeviceTree(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockComposition := mock.NewMockCompositionRepository(ctrl)
+	s := &Server{composition: mockComposition}
+
+	tree := &devices.DeviceNode{
+		Device: devices.Device{Id: 1, Name: "rig"},
+		Children: []devices.DeviceNode{
+			{Device: devices.Device{Id: 2, Name: "phone", ParentId: 1}, Children: []devices.DeviceNode{}},
+		},
+	}
+	mockComposition.EXPECT().
+		DeviceTree(gomock.Any(), int64(1)).
+		Return(tree, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/tree", nil)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.DeviceTree(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", w.Code)
+	}
+
+	var got devices.DeviceNode
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Id != 1 || len(got.Children) != 1 || got.Children[0].ParentId != 1 {
+		t.Errorf("expected the rig with one part; got %+v", got)
+	}
+}
+
+func TestDevicesByState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	mockRepo.EXPECT().
+		GetByState(gomock.Any(), devices.InUse).
+		Return([]devices.Device{}, nil).
+		Times(2)
+
+	for _, state := range []string{"in_use", "1"} {
+		r := httptest.NewRequest(http.MethodGet, "/devices/state/"+state, nil)
+		r = withURLParam(r, "state", state)
+		w := httptest.NewRecorder()
+
+		s.DevicesByState(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status OK for %s; got %v", state, w.Code)
+		}
+	}
+}
+
+func TestDevicesByState_Unknown(t *testing.T) {
+	s := &Server{}
+
+	for _, state := range []string{"broken", "42"} {
+		r := httptest.NewRequest(http.MethodGet, "/devices/state/"+state, nil)
+		r = withURLParam(r, "state", state)
+		w := httptest.NewRecorder()
+
+		s.DevicesByState(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status Bad Request for %s; got %v", state, w.Code)
+		}
+	}
+}
+
+func TestCreateDevice_Invalid(t *testing.T) {
+	s := &Server{}
+
+	body := strings.NewReader(`{"name":" ","serial_number":"SN 1","labels":{"team":"q a"}}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices", body)
+	w := httptest.NewRecorder()
+
+	s.CreateDevice(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status Unprocessable Entity; got %v", w.Code)
+	}
+
+	var got struct {
+		Field  string               `json:"field"`
+		Fields []devices.FieldError `json:"fields"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields []string
+	for _, f := range got.Fields {
+		fields = append(fields, f.Field)
+	}
+	want := "name,brand,serial_number,labels.team"
+	if strings.Join(fields, ",") != want || got.Field != "name" {
+		t.Errorf("got fields %v, first %q; want %s", fields, got.Field, want)
+	}
+}
+
+func TestCreateDevice_UnknownFields(t *testing.T) {
+	s := &Server{}
+
+	body := strings.NewReader(`{"name":"Device1","brand":"Brand1","colour":"red","serial":"SN-1"}`)
+	r := httptest.NewRequest(http.MethodPost, "/devices", body)
+	w := httptest.NewRecorder()
+
+	s.CreateDevice(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status Unprocessable Entity; got %v", w.Code)
+	}
+	for _, field := range []string{`"colour"`, `"serial"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("expected %s to be listed; got %s", field, w.Body.String())
+		}
+	}
+}
+
+func TestCreateDevice_MalformedJSON(t *testing.T) {
+	s := &Server{}
+
+	r := httptest.NewRequest(http.MethodPost, "/devices", strings.NewReader(`{"name":`))
+	w := httptest.NewRecorder()
+
+	s.CreateDevice(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request; got %v", w.Code)
+	}
+}
+
+func TestUpdateDevice_Invalid(t *testing.T) {
+	s := &Server{}
+
+	body := strings.NewReader(`{"name":"Device1","brand":"","state":"broken"}`)
+	r := httptest.NewRequest(http.MethodPut, "/devices/1", body)
+	r = withURLParam(r, "id", "1")
+	w := httptest.NewRecorder()
+
+	s.UpdateDevice(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status Unprocessable Entity; got %v", w.Code)
+	}
+	for _, field := range []string{`"field":"brand"`, `"field":"state"`} {
+		if !strings.Contains(w.Body.String(), field) {
+			t.Errorf("expected %s to be listed; got %s", field, w.Body.String())
+		}
+	}
+}
+
+func TestDeleteDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock.NewMockRepository(ctrl)
+	s := &Server{devices: devices.NewService(mockRepo)}
+
+	inUse := devices.Device{Id: 1, State: devices.InUse, Version: 2}
+	available := devices.Device{Id: 2, State: devices.Available, Version: 3}
+	mockRepo.EXPECT().GetById(gomock.Any(), int64(1)).Return(&inUse, nil)
+	mockRepo.EXPECT().GetById(gomock.Any(), int64(2)).Return(&available, nil)
+	mockRepo.EXPECT().Delete(gomock.Any(), available, int64(3)).Return(nil, nil)
+
+	// The state sent in the body of the legacy route is ignored.
+	r := httptest.NewRequest(http.MethodDelete, "/devices/delete/", strings.NewReader(`{"id":1,"state":"available"}`))
+	w := httptest.NewRecorder()
+	s.DeleteDevice(w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict for an in-use device; got %v", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodDelete, "/devices/2", nil)
+	r = withURLParam(r, "id", "2")
+	w = httptest.NewRecorder()
+	s.DeleteDevice(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status No Content; got %v", w.Code)
+	}
+}
+
+func TestRoutes_MemoryRepository(t *testing.T) {
+	h := newServer(memory.NewRepository()).RegisterRoutes()
+
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodPost, "/devices", `{"name":"Pixel 8","brand":"Google","labels":{"team":"qa"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status Created; got %v: %s", w.Code, w.Body)
+	}
+
+	w = do(http.MethodPut, "/devices/1", `{"name":"Pixel 8","brand":"Google","state":"in_use"}`, "If-Match", `"1"`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected status OK with ETag \"2\"; got %v %s: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+
+	w = do(http.MethodGet, "/devices/state/in_use", "")
+	if !strings.Contains(w.Body.String(), `"name":"Pixel 8"`) {
+		t.Errorf("expected the device in use; got %s", w.Body)
+	}
+
+	w = do(http.MethodDelete, "/devices/1", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status Conflict for an in-use device; got %v", w.Code)
+	}
+
+	w = do(http.MethodGet, "/devices?selector=team=qa", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"name"`) {
+		t.Errorf("expected no device labelled team=qa after the update; got %v: %s", w.Code, w.Body)
+	}
+
+	w = do(http.MethodGet, "/devices/2", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status Not Found; got %v", w.Code)
+	}
+}
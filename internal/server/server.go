@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strconv"
@@ -10,22 +12,39 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"devices_api/internal/devices"
+	"devices_api/internal/devices/memory"
 	repo "devices_api/internal/devices/postgres"
+	"devices_api/internal/devices/sqlite"
 )
 
 type Server struct {
 	port int
 
-	//db database.Service
-	db devices.Repository
+	// devices applies the domain rules to the core device operations.
+	devices *devices.Service
+
+	// Optional repository capabilities. Routes for a capability are only
+	// registered when the repository implements it.
+	finder       devices.Finder
+	labels       devices.LabelRepository
+	history      devices.HistoryReader
+	leases       devices.LeaseRepository
+	reservations devices.ReservationRepository
+	trash        devices.TrashRepository
+	maintenance  devices.MaintenanceRepository
+	brands       devices.BrandRepository
+	models       devices.ModelRepository
+	locations    devices.LocationRepository
+	directory    devices.DirectoryRepository
+	ownership    devices.OwnershipRepository
+	groups       devices.GroupRepository
+	composition  devices.CompositionRepository
 }
 
 func NewServer() *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	NewServer := &Server{
-		port: port,
-		db:   repo.NewRepository(),
-	}
+	NewServer := newServer(newRepository(os.Getenv("DB_BACKEND")))
+	NewServer.port = port
 
 	// Declare Server config
 	server := &http.Server{
@@ -36,5 +55,64 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Background jobs stop when the server shuts down.
+	ctx, cancel := context.WithCancel(context.Background())
+	server.RegisterOnShutdown(cancel)
+	NewServer.startJobs(ctx)
+
 	return server
 }
+
+// newServer serves the repository db. Routes of the optional capabilities
+// are registered for those that db implements.
+func newServer(db devices.Repository) *Server {
+	s := &Server{devices: devices.NewService(db)}
+	s.finder, _ = db.(devices.Finder)
+	s.labels, _ = db.(devices.LabelRepository)
+	s.history, _ = db.(devices.HistoryReader)
+	s.leases, _ = db.(devices.LeaseRepository)
+	s.reservations, _ = db.(devices.ReservationRepository)
+	s.trash, _ = db.(devices.TrashRepository)
+	s.maintenance, _ = db.(devices.MaintenanceRepository)
+	s.brands, _ = db.(devices.BrandRepository)
+	s.models, _ = db.(devices.ModelRepository)
+	s.locations, _ = db.(devices.LocationRepository)
+	s.directory, _ = db.(devices.DirectoryRepository)
+	s.ownership, _ = db.(devices.OwnershipRepository)
+	s.groups, _ = db.(devices.GroupRepository)
+	s.composition, _ = db.(devices.CompositionRepository)
+	return s
+}
+
+// newRepository opens the repository backend named by DB_BACKEND. Postgres
+// is the default; "sqlite" stores everything in the file DB_PATH, and
+// "memory" keeps it in the process, for local demos and tests without a
+// database.
+func newRepository(backend string) devices.Repository {
+	switch backend {
+	case "", "postgres":
+		cfg, err := repo.ConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		db, err := repo.New(context.Background(), cfg)
+		if err != nil {
+			log.Fatalf("opening postgres database: %v", err)
+		}
+		return db
+	case "sqlite":
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "devices.db"
+		}
+		db, err := sqlite.New(context.Background(), path)
+		if err != nil {
+			log.Fatalf("opening sqlite database %s: %v", path, err)
+		}
+		return db
+	case "memory":
+		return memory.NewRepository()
+	}
+	log.Fatalf("unknown DB_BACKEND %q, want postgres, sqlite or memory", backend)
+	return nil
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"devices_api/internal/server/rest"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+// TrashedDevices swagger:route GET /devices/trash devices trashedDevices
+//
+// Get the deleted devices that have not been purged yet.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: []device
+//	    500: internalServerError
+func (s *Server) TrashedDevices(w http.ResponseWriter, r *http.Request) {
+	dd, err := s.trash.Trash(r.Context())
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.RenderList(w, r, rest.NewDeviceListResponse(dd))
+}
+
+// RestoreDevice swagger:route POST /devices/{id}/restore devices restoreDevice
+//
+// Restores a deleted device from the trash. A device whose serial number or
+// asset tag was registered again while it was in the trash cannot be
+// restored.
+//
+// Responses:
+//
+//	default: genericError
+//	    200: device
+//	    404: genericError
+//	    409: genericError
+//	    500: internalServerError
+func (s *Server) RestoreDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.trash.Restore(r.Context(), id)
+	if err != nil {
+		log.Println(w, r, err.Error())
+		http.Error(w, err.Error(), statusFor(err))
+		return
+	}
+
+	setETag(w, d)
+	render.Render(w, r, rest.NewDeviceResponse(d))
+}
+
+// purgeTrash permanently removes devices that have been in the trash for
+// longer than the retention period.
+func (s *Server) purgeTrash(retention time.Duration) func(context.Context) {
+	return func(ctx context.Context) {
+		n, err := s.trash.Purge(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("trash purge: %v", err)
+			return
+		}
+
+		if n > 0 {
+			log.Printf("trash purge: removed %d devices deleted more than %s ago", n, retention)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	sql "database/sql"
 	devices "devices_api/internal/devices"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -87,18 +88,33 @@ func (mr *MockRepositoryMockRecorder) Create(ctx, cd any) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockRepository) Delete(ctx context.Context, d devices.Device) (sql.Result, error) {
+func (m *MockRepository) Delete(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, d)
+	ret := m.ctrl.Call(m, "Delete", ctx, d, version)
 	ret0, _ := ret[0].(sql.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockRepositoryMockRecorder) Delete(ctx, d any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Delete(ctx, d, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, d, version)
+}
+
+// GetByAssetTag mocks base method.
+func (m *MockRepository) GetByAssetTag(ctx context.Context, tag string) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByAssetTag", ctx, tag)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByAssetTag indicates an expected call of GetByAssetTag.
+func (mr *MockRepositoryMockRecorder) GetByAssetTag(ctx, tag any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAssetTag", reflect.TypeOf((*MockRepository)(nil).GetByAssetTag), ctx, tag)
 }
 
 // GetByBrand mocks base method.
@@ -161,18 +177,18 @@ func (mr *MockRepositoryMockRecorder) Health() *gomock.Call {
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(ctx context.Context, d devices.Device) (sql.Result, error) {
+func (m *MockRepository) Update(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, d)
+	ret := m.ctrl.Call(m, "Update", ctx, d, version)
 	ret0, _ := ret[0].(sql.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(ctx, d any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, d, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, d, version)
 }
 
 // MockWriter is a mock of Writer interface.
@@ -215,33 +231,33 @@ func (mr *MockWriterMockRecorder) Create(ctx, cd any) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockWriter) Delete(ctx context.Context, d devices.Device) (sql.Result, error) {
+func (m *MockWriter) Delete(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, d)
+	ret := m.ctrl.Call(m, "Delete", ctx, d, version)
 	ret0, _ := ret[0].(sql.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockWriterMockRecorder) Delete(ctx, d any) *gomock.Call {
+func (mr *MockWriterMockRecorder) Delete(ctx, d, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWriter)(nil).Delete), ctx, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWriter)(nil).Delete), ctx, d, version)
 }
 
 // Update mocks base method.
-func (m *MockWriter) Update(ctx context.Context, d devices.Device) (sql.Result, error) {
+func (m *MockWriter) Update(ctx context.Context, d devices.Device, version int64) (sql.Result, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, d)
+	ret := m.ctrl.Call(m, "Update", ctx, d, version)
 	ret0, _ := ret[0].(sql.Result)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockWriterMockRecorder) Update(ctx, d any) *gomock.Call {
+func (mr *MockWriterMockRecorder) Update(ctx, d, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWriter)(nil).Update), ctx, d)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWriter)(nil).Update), ctx, d, version)
 }
 
 // MockReader is a mock of Reader interface.
@@ -283,6 +299,21 @@ func (mr *MockReaderMockRecorder) All(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockReader)(nil).All), ctx)
 }
 
+// GetByAssetTag mocks base method.
+func (m *MockReader) GetByAssetTag(ctx context.Context, tag string) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByAssetTag", ctx, tag)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByAssetTag indicates an expected call of GetByAssetTag.
+func (mr *MockReaderMockRecorder) GetByAssetTag(ctx, tag any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAssetTag", reflect.TypeOf((*MockReader)(nil).GetByAssetTag), ctx, tag)
+}
+
 // GetByBrand mocks base method.
 func (m *MockReader) GetByBrand(ctx context.Context, b string) ([]devices.Device, error) {
 	m.ctrl.T.Helper()
@@ -328,32 +359,1290 @@ func (mr *MockReaderMockRecorder) GetByState(ctx, s any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByState", reflect.TypeOf((*MockReader)(nil).GetByState), ctx, s)
 }
 
-// MockService is a mock of Service interface.
-type MockService struct {
+// MockFinder is a mock of Finder interface.
+type MockFinder struct {
+	ctrl     *gomock.Controller
+	recorder *MockFinderMockRecorder
+	isgomock struct{}
+}
+
+// MockFinderMockRecorder is the mock recorder for MockFinder.
+type MockFinderMockRecorder struct {
+	mock *MockFinder
+}
+
+// NewMockFinder creates a new mock instance.
+func NewMockFinder(ctrl *gomock.Controller) *MockFinder {
+	mock := &MockFinder{ctrl: ctrl}
+	mock.recorder = &MockFinderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFinder) EXPECT() *MockFinderMockRecorder {
+	return m.recorder
+}
+
+// Find mocks base method.
+func (m *MockFinder) Find(ctx context.Context, f devices.DeviceFilter) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, f)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockFinderMockRecorder) Find(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockFinder)(nil).Find), ctx, f)
+}
+
+// MockLabelRepository is a mock of LabelRepository interface.
+type MockLabelRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLabelRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLabelRepositoryMockRecorder is the mock recorder for MockLabelRepository.
+type MockLabelRepositoryMockRecorder struct {
+	mock *MockLabelRepository
+}
+
+// NewMockLabelRepository creates a new mock instance.
+func NewMockLabelRepository(ctrl *gomock.Controller) *MockLabelRepository {
+	mock := &MockLabelRepository{ctrl: ctrl}
+	mock.recorder = &MockLabelRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLabelRepository) EXPECT() *MockLabelRepositoryMockRecorder {
+	return m.recorder
+}
+
+// UpdateLabels mocks base method.
+func (m *MockLabelRepository) UpdateLabels(ctx context.Context, deviceId int64, set map[string]string, remove []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLabels", ctx, deviceId, set, remove)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateLabels indicates an expected call of UpdateLabels.
+func (mr *MockLabelRepositoryMockRecorder) UpdateLabels(ctx, deviceId, set, remove any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLabels", reflect.TypeOf((*MockLabelRepository)(nil).UpdateLabels), ctx, deviceId, set, remove)
+}
+
+// MockBrandRepository is a mock of BrandRepository interface.
+type MockBrandRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockBrandRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockBrandRepositoryMockRecorder is the mock recorder for MockBrandRepository.
+type MockBrandRepositoryMockRecorder struct {
+	mock *MockBrandRepository
+}
+
+// NewMockBrandRepository creates a new mock instance.
+func NewMockBrandRepository(ctrl *gomock.Controller) *MockBrandRepository {
+	mock := &MockBrandRepository{ctrl: ctrl}
+	mock.recorder = &MockBrandRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBrandRepository) EXPECT() *MockBrandRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddBrandAlias mocks base method.
+func (m *MockBrandRepository) AddBrandAlias(ctx context.Context, id int64, alias string) (*devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBrandAlias", ctx, id, alias)
+	ret0, _ := ret[0].(*devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddBrandAlias indicates an expected call of AddBrandAlias.
+func (mr *MockBrandRepositoryMockRecorder) AddBrandAlias(ctx, id, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBrandAlias", reflect.TypeOf((*MockBrandRepository)(nil).AddBrandAlias), ctx, id, alias)
+}
+
+// Brands mocks base method.
+func (m *MockBrandRepository) Brands(ctx context.Context) ([]devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Brands", ctx)
+	ret0, _ := ret[0].([]devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Brands indicates an expected call of Brands.
+func (mr *MockBrandRepositoryMockRecorder) Brands(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Brands", reflect.TypeOf((*MockBrandRepository)(nil).Brands), ctx)
+}
+
+// CreateBrand mocks base method.
+func (m *MockBrandRepository) CreateBrand(ctx context.Context, cb devices.CreateBrand) (*devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBrand", ctx, cb)
+	ret0, _ := ret[0].(*devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBrand indicates an expected call of CreateBrand.
+func (mr *MockBrandRepositoryMockRecorder) CreateBrand(ctx, cb any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBrand", reflect.TypeOf((*MockBrandRepository)(nil).CreateBrand), ctx, cb)
+}
+
+// DeleteBrand mocks base method.
+func (m *MockBrandRepository) DeleteBrand(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBrand", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBrand indicates an expected call of DeleteBrand.
+func (mr *MockBrandRepositoryMockRecorder) DeleteBrand(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBrand", reflect.TypeOf((*MockBrandRepository)(nil).DeleteBrand), ctx, id)
+}
+
+// GetBrand mocks base method.
+func (m *MockBrandRepository) GetBrand(ctx context.Context, b string) (*devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBrand", ctx, b)
+	ret0, _ := ret[0].(*devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBrand indicates an expected call of GetBrand.
+func (mr *MockBrandRepositoryMockRecorder) GetBrand(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBrand", reflect.TypeOf((*MockBrandRepository)(nil).GetBrand), ctx, b)
+}
+
+// RemoveBrandAlias mocks base method.
+func (m *MockBrandRepository) RemoveBrandAlias(ctx context.Context, id int64, alias string) (*devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveBrandAlias", ctx, id, alias)
+	ret0, _ := ret[0].(*devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveBrandAlias indicates an expected call of RemoveBrandAlias.
+func (mr *MockBrandRepositoryMockRecorder) RemoveBrandAlias(ctx, id, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveBrandAlias", reflect.TypeOf((*MockBrandRepository)(nil).RemoveBrandAlias), ctx, id, alias)
+}
+
+// RenameBrand mocks base method.
+func (m *MockBrandRepository) RenameBrand(ctx context.Context, id int64, name string) (*devices.Brand, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameBrand", ctx, id, name)
+	ret0, _ := ret[0].(*devices.Brand)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameBrand indicates an expected call of RenameBrand.
+func (mr *MockBrandRepositoryMockRecorder) RenameBrand(ctx, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameBrand", reflect.TypeOf((*MockBrandRepository)(nil).RenameBrand), ctx, id, name)
+}
+
+// MockModelRepository is a mock of ModelRepository interface.
+type MockModelRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockModelRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockModelRepositoryMockRecorder is the mock recorder for MockModelRepository.
+type MockModelRepositoryMockRecorder struct {
+	mock *MockModelRepository
+}
+
+// NewMockModelRepository creates a new mock instance.
+func NewMockModelRepository(ctrl *gomock.Controller) *MockModelRepository {
+	mock := &MockModelRepository{ctrl: ctrl}
+	mock.recorder = &MockModelRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModelRepository) EXPECT() *MockModelRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateModel mocks base method.
+func (m *MockModelRepository) CreateModel(ctx context.Context, brandId int64, cm devices.CreateModel) (*devices.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateModel", ctx, brandId, cm)
+	ret0, _ := ret[0].(*devices.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateModel indicates an expected call of CreateModel.
+func (mr *MockModelRepositoryMockRecorder) CreateModel(ctx, brandId, cm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateModel", reflect.TypeOf((*MockModelRepository)(nil).CreateModel), ctx, brandId, cm)
+}
+
+// DeleteModel mocks base method.
+func (m *MockModelRepository) DeleteModel(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteModel", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteModel indicates an expected call of DeleteModel.
+func (mr *MockModelRepositoryMockRecorder) DeleteModel(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteModel", reflect.TypeOf((*MockModelRepository)(nil).DeleteModel), ctx, id)
+}
+
+// GetByModel mocks base method.
+func (m *MockModelRepository) GetByModel(ctx context.Context, modelId int64) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByModel", ctx, modelId)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByModel indicates an expected call of GetByModel.
+func (mr *MockModelRepositoryMockRecorder) GetByModel(ctx, modelId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByModel", reflect.TypeOf((*MockModelRepository)(nil).GetByModel), ctx, modelId)
+}
+
+// GetModel mocks base method.
+func (m *MockModelRepository) GetModel(ctx context.Context, id int64) (*devices.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModel", ctx, id)
+	ret0, _ := ret[0].(*devices.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetModel indicates an expected call of GetModel.
+func (mr *MockModelRepositoryMockRecorder) GetModel(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModel", reflect.TypeOf((*MockModelRepository)(nil).GetModel), ctx, id)
+}
+
+// Inventory mocks base method.
+func (m *MockModelRepository) Inventory(ctx context.Context, brandId int64) ([]devices.ModelInventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Inventory", ctx, brandId)
+	ret0, _ := ret[0].([]devices.ModelInventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Inventory indicates an expected call of Inventory.
+func (mr *MockModelRepositoryMockRecorder) Inventory(ctx, brandId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Inventory", reflect.TypeOf((*MockModelRepository)(nil).Inventory), ctx, brandId)
+}
+
+// Models mocks base method.
+func (m *MockModelRepository) Models(ctx context.Context, brandId int64) ([]devices.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Models", ctx, brandId)
+	ret0, _ := ret[0].([]devices.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Models indicates an expected call of Models.
+func (mr *MockModelRepositoryMockRecorder) Models(ctx, brandId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Models", reflect.TypeOf((*MockModelRepository)(nil).Models), ctx, brandId)
+}
+
+// MockLocationRepository is a mock of LocationRepository interface.
+type MockLocationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLocationRepositoryMockRecorder is the mock recorder for MockLocationRepository.
+type MockLocationRepositoryMockRecorder struct {
+	mock *MockLocationRepository
+}
+
+// NewMockLocationRepository creates a new mock instance.
+func NewMockLocationRepository(ctrl *gomock.Controller) *MockLocationRepository {
+	mock := &MockLocationRepository{ctrl: ctrl}
+	mock.recorder = &MockLocationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocationRepository) EXPECT() *MockLocationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateLocation mocks base method.
+func (m *MockLocationRepository) CreateLocation(ctx context.Context, cl devices.CreateLocation) (*devices.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocation", ctx, cl)
+	ret0, _ := ret[0].(*devices.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocation indicates an expected call of CreateLocation.
+func (mr *MockLocationRepositoryMockRecorder) CreateLocation(ctx, cl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*MockLocationRepository)(nil).CreateLocation), ctx, cl)
+}
+
+// DeleteLocation mocks base method.
+func (m *MockLocationRepository) DeleteLocation(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocation", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLocation indicates an expected call of DeleteLocation.
+func (mr *MockLocationRepositoryMockRecorder) DeleteLocation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocation", reflect.TypeOf((*MockLocationRepository)(nil).DeleteLocation), ctx, id)
+}
+
+// GetByLocation mocks base method.
+func (m *MockLocationRepository) GetByLocation(ctx context.Context, locationId int64, includeChildren bool) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByLocation", ctx, locationId, includeChildren)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByLocation indicates an expected call of GetByLocation.
+func (mr *MockLocationRepositoryMockRecorder) GetByLocation(ctx, locationId, includeChildren any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByLocation", reflect.TypeOf((*MockLocationRepository)(nil).GetByLocation), ctx, locationId, includeChildren)
+}
+
+// GetLocation mocks base method.
+func (m *MockLocationRepository) GetLocation(ctx context.Context, id int64) (*devices.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocation", ctx, id)
+	ret0, _ := ret[0].(*devices.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocation indicates an expected call of GetLocation.
+func (mr *MockLocationRepositoryMockRecorder) GetLocation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockLocationRepository)(nil).GetLocation), ctx, id)
+}
+
+// Locations mocks base method.
+func (m *MockLocationRepository) Locations(ctx context.Context) ([]devices.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Locations", ctx)
+	ret0, _ := ret[0].([]devices.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Locations indicates an expected call of Locations.
+func (mr *MockLocationRepositoryMockRecorder) Locations(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Locations", reflect.TypeOf((*MockLocationRepository)(nil).Locations), ctx)
+}
+
+// MoveDevice mocks base method.
+func (m_2 *MockLocationRepository) MoveDevice(ctx context.Context, deviceId int64, m devices.Move, version int64) (*devices.Device, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "MoveDevice", ctx, deviceId, m, version)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MoveDevice indicates an expected call of MoveDevice.
+func (mr *MockLocationRepositoryMockRecorder) MoveDevice(ctx, deviceId, m, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveDevice", reflect.TypeOf((*MockLocationRepository)(nil).MoveDevice), ctx, deviceId, m, version)
+}
+
+// UpdateLocation mocks base method.
+func (m *MockLocationRepository) UpdateLocation(ctx context.Context, id int64, ul devices.UpdateLocation) (*devices.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLocation", ctx, id, ul)
+	ret0, _ := ret[0].(*devices.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateLocation indicates an expected call of UpdateLocation.
+func (mr *MockLocationRepositoryMockRecorder) UpdateLocation(ctx, id, ul any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLocation", reflect.TypeOf((*MockLocationRepository)(nil).UpdateLocation), ctx, id, ul)
+}
+
+// MockDirectoryRepository is a mock of DirectoryRepository interface.
+type MockDirectoryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDirectoryRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockDirectoryRepositoryMockRecorder is the mock recorder for MockDirectoryRepository.
+type MockDirectoryRepositoryMockRecorder struct {
+	mock *MockDirectoryRepository
+}
+
+// NewMockDirectoryRepository creates a new mock instance.
+func NewMockDirectoryRepository(ctrl *gomock.Controller) *MockDirectoryRepository {
+	mock := &MockDirectoryRepository{ctrl: ctrl}
+	mock.recorder = &MockDirectoryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDirectoryRepository) EXPECT() *MockDirectoryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddTeamMember mocks base method.
+func (m_2 *MockDirectoryRepository) AddTeamMember(ctx context.Context, teamId int64, m devices.Membership) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "AddTeamMember", ctx, teamId, m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddTeamMember indicates an expected call of AddTeamMember.
+func (mr *MockDirectoryRepositoryMockRecorder) AddTeamMember(ctx, teamId, m any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTeamMember", reflect.TypeOf((*MockDirectoryRepository)(nil).AddTeamMember), ctx, teamId, m)
+}
+
+// CreateTeam mocks base method.
+func (m *MockDirectoryRepository) CreateTeam(ctx context.Context, ct devices.CreateTeam) (*devices.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTeam", ctx, ct)
+	ret0, _ := ret[0].(*devices.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTeam indicates an expected call of CreateTeam.
+func (mr *MockDirectoryRepositoryMockRecorder) CreateTeam(ctx, ct any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTeam", reflect.TypeOf((*MockDirectoryRepository)(nil).CreateTeam), ctx, ct)
+}
+
+// CreateUser mocks base method.
+func (m *MockDirectoryRepository) CreateUser(ctx context.Context, cu devices.CreateUser) (*devices.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, cu)
+	ret0, _ := ret[0].(*devices.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockDirectoryRepositoryMockRecorder) CreateUser(ctx, cu any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockDirectoryRepository)(nil).CreateUser), ctx, cu)
+}
+
+// GetTeam mocks base method.
+func (m *MockDirectoryRepository) GetTeam(ctx context.Context, id int64) (*devices.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTeam", ctx, id)
+	ret0, _ := ret[0].(*devices.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTeam indicates an expected call of GetTeam.
+func (mr *MockDirectoryRepositoryMockRecorder) GetTeam(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTeam", reflect.TypeOf((*MockDirectoryRepository)(nil).GetTeam), ctx, id)
+}
+
+// GetUser mocks base method.
+func (m *MockDirectoryRepository) GetUser(ctx context.Context, id int64) (*devices.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, id)
+	ret0, _ := ret[0].(*devices.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockDirectoryRepositoryMockRecorder) GetUser(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockDirectoryRepository)(nil).GetUser), ctx, id)
+}
+
+// RemoveTeamMember mocks base method.
+func (m *MockDirectoryRepository) RemoveTeamMember(ctx context.Context, teamId, userId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveTeamMember", ctx, teamId, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveTeamMember indicates an expected call of RemoveTeamMember.
+func (mr *MockDirectoryRepositoryMockRecorder) RemoveTeamMember(ctx, teamId, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveTeamMember", reflect.TypeOf((*MockDirectoryRepository)(nil).RemoveTeamMember), ctx, teamId, userId)
+}
+
+// TeamMembers mocks base method.
+func (m *MockDirectoryRepository) TeamMembers(ctx context.Context, teamId int64) ([]devices.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TeamMembers", ctx, teamId)
+	ret0, _ := ret[0].([]devices.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TeamMembers indicates an expected call of TeamMembers.
+func (mr *MockDirectoryRepositoryMockRecorder) TeamMembers(ctx, teamId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TeamMembers", reflect.TypeOf((*MockDirectoryRepository)(nil).TeamMembers), ctx, teamId)
+}
+
+// Teams mocks base method.
+func (m *MockDirectoryRepository) Teams(ctx context.Context) ([]devices.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Teams", ctx)
+	ret0, _ := ret[0].([]devices.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Teams indicates an expected call of Teams.
+func (mr *MockDirectoryRepositoryMockRecorder) Teams(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Teams", reflect.TypeOf((*MockDirectoryRepository)(nil).Teams), ctx)
+}
+
+// Users mocks base method.
+func (m *MockDirectoryRepository) Users(ctx context.Context) ([]devices.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Users", ctx)
+	ret0, _ := ret[0].([]devices.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Users indicates an expected call of Users.
+func (mr *MockDirectoryRepositoryMockRecorder) Users(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Users", reflect.TypeOf((*MockDirectoryRepository)(nil).Users), ctx)
+}
+
+// MockOwnershipRepository is a mock of OwnershipRepository interface.
+type MockOwnershipRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOwnershipRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockOwnershipRepositoryMockRecorder is the mock recorder for MockOwnershipRepository.
+type MockOwnershipRepositoryMockRecorder struct {
+	mock *MockOwnershipRepository
+}
+
+// NewMockOwnershipRepository creates a new mock instance.
+func NewMockOwnershipRepository(ctrl *gomock.Controller) *MockOwnershipRepository {
+	mock := &MockOwnershipRepository{ctrl: ctrl}
+	mock.recorder = &MockOwnershipRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOwnershipRepository) EXPECT() *MockOwnershipRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AssignDevice mocks base method.
+func (m *MockOwnershipRepository) AssignDevice(ctx context.Context, deviceId int64, a devices.Assignment, version int64) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignDevice", ctx, deviceId, a, version)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignDevice indicates an expected call of AssignDevice.
+func (mr *MockOwnershipRepositoryMockRecorder) AssignDevice(ctx, deviceId, a, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignDevice", reflect.TypeOf((*MockOwnershipRepository)(nil).AssignDevice), ctx, deviceId, a, version)
+}
+
+// GetByAssignee mocks base method.
+func (m *MockOwnershipRepository) GetByAssignee(ctx context.Context, userId int64) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByAssignee", ctx, userId)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByAssignee indicates an expected call of GetByAssignee.
+func (mr *MockOwnershipRepositoryMockRecorder) GetByAssignee(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAssignee", reflect.TypeOf((*MockOwnershipRepository)(nil).GetByAssignee), ctx, userId)
+}
+
+// GetByOwner mocks base method.
+func (m *MockOwnershipRepository) GetByOwner(ctx context.Context, teamId int64) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByOwner", ctx, teamId)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByOwner indicates an expected call of GetByOwner.
+func (mr *MockOwnershipRepositoryMockRecorder) GetByOwner(ctx, teamId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByOwner", reflect.TypeOf((*MockOwnershipRepository)(nil).GetByOwner), ctx, teamId)
+}
+
+// RequestTransfer mocks base method.
+func (m *MockOwnershipRepository) RequestTransfer(ctx context.Context, deviceId int64, ct devices.CreateTransfer) (*devices.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestTransfer", ctx, deviceId, ct)
+	ret0, _ := ret[0].(*devices.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestTransfer indicates an expected call of RequestTransfer.
+func (mr *MockOwnershipRepositoryMockRecorder) RequestTransfer(ctx, deviceId, ct any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestTransfer", reflect.TypeOf((*MockOwnershipRepository)(nil).RequestTransfer), ctx, deviceId, ct)
+}
+
+// ResolveTransfer mocks base method.
+func (m *MockOwnershipRepository) ResolveTransfer(ctx context.Context, transferId int64, tr devices.TransferResolution, status devices.TransferStatus) (*devices.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveTransfer", ctx, transferId, tr, status)
+	ret0, _ := ret[0].(*devices.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveTransfer indicates an expected call of ResolveTransfer.
+func (mr *MockOwnershipRepositoryMockRecorder) ResolveTransfer(ctx, transferId, tr, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveTransfer", reflect.TypeOf((*MockOwnershipRepository)(nil).ResolveTransfer), ctx, transferId, tr, status)
+}
+
+// Transfers mocks base method.
+func (m *MockOwnershipRepository) Transfers(ctx context.Context, f devices.TransferFilter) ([]devices.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfers", ctx, f)
+	ret0, _ := ret[0].([]devices.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfers indicates an expected call of Transfers.
+func (mr *MockOwnershipRepositoryMockRecorder) Transfers(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfers", reflect.TypeOf((*MockOwnershipRepository)(nil).Transfers), ctx, f)
+}
+
+// MockGroupRepository is a mock of GroupRepository interface.
+type MockGroupRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockGroupRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockGroupRepositoryMockRecorder is the mock recorder for MockGroupRepository.
+type MockGroupRepositoryMockRecorder struct {
+	mock *MockGroupRepository
+}
+
+// NewMockGroupRepository creates a new mock instance.
+func NewMockGroupRepository(ctrl *gomock.Controller) *MockGroupRepository {
+	mock := &MockGroupRepository{ctrl: ctrl}
+	mock.recorder = &MockGroupRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGroupRepository) EXPECT() *MockGroupRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddGroupMembers mocks base method.
+func (m *MockGroupRepository) AddGroupMembers(ctx context.Context, groupId int64, deviceIds []int64) (*devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddGroupMembers", ctx, groupId, deviceIds)
+	ret0, _ := ret[0].(*devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddGroupMembers indicates an expected call of AddGroupMembers.
+func (mr *MockGroupRepositoryMockRecorder) AddGroupMembers(ctx, groupId, deviceIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGroupMembers", reflect.TypeOf((*MockGroupRepository)(nil).AddGroupMembers), ctx, groupId, deviceIds)
+}
+
+// ApplyToGroup mocks base method.
+func (m *MockGroupRepository) ApplyToGroup(ctx context.Context, groupId int64, a devices.GroupAction) (*devices.GroupReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyToGroup", ctx, groupId, a)
+	ret0, _ := ret[0].(*devices.GroupReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyToGroup indicates an expected call of ApplyToGroup.
+func (mr *MockGroupRepositoryMockRecorder) ApplyToGroup(ctx, groupId, a any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyToGroup", reflect.TypeOf((*MockGroupRepository)(nil).ApplyToGroup), ctx, groupId, a)
+}
+
+// CreateGroup mocks base method.
+func (m *MockGroupRepository) CreateGroup(ctx context.Context, cg devices.CreateGroup) (*devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", ctx, cg)
+	ret0, _ := ret[0].(*devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *MockGroupRepositoryMockRecorder) CreateGroup(ctx, cg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockGroupRepository)(nil).CreateGroup), ctx, cg)
+}
+
+// DeleteGroup mocks base method.
+func (m *MockGroupRepository) DeleteGroup(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *MockGroupRepositoryMockRecorder) DeleteGroup(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*MockGroupRepository)(nil).DeleteGroup), ctx, id)
+}
+
+// GetGroup mocks base method.
+func (m *MockGroupRepository) GetGroup(ctx context.Context, id int64) (*devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroup", ctx, id)
+	ret0, _ := ret[0].(*devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroup indicates an expected call of GetGroup.
+func (mr *MockGroupRepositoryMockRecorder) GetGroup(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroup", reflect.TypeOf((*MockGroupRepository)(nil).GetGroup), ctx, id)
+}
+
+// GroupMembers mocks base method.
+func (m *MockGroupRepository) GroupMembers(ctx context.Context, groupId int64) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GroupMembers", ctx, groupId)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GroupMembers indicates an expected call of GroupMembers.
+func (mr *MockGroupRepositoryMockRecorder) GroupMembers(ctx, groupId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GroupMembers", reflect.TypeOf((*MockGroupRepository)(nil).GroupMembers), ctx, groupId)
+}
+
+// Groups mocks base method.
+func (m *MockGroupRepository) Groups(ctx context.Context) ([]devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Groups", ctx)
+	ret0, _ := ret[0].([]devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Groups indicates an expected call of Groups.
+func (mr *MockGroupRepositoryMockRecorder) Groups(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Groups", reflect.TypeOf((*MockGroupRepository)(nil).Groups), ctx)
+}
+
+// RemoveGroupMember mocks base method.
+func (m *MockGroupRepository) RemoveGroupMember(ctx context.Context, groupId, deviceId int64) (*devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveGroupMember", ctx, groupId, deviceId)
+	ret0, _ := ret[0].(*devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveGroupMember indicates an expected call of RemoveGroupMember.
+func (mr *MockGroupRepositoryMockRecorder) RemoveGroupMember(ctx, groupId, deviceId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveGroupMember", reflect.TypeOf((*MockGroupRepository)(nil).RemoveGroupMember), ctx, groupId, deviceId)
+}
+
+// UpdateGroup mocks base method.
+func (m *MockGroupRepository) UpdateGroup(ctx context.Context, id int64, cg devices.CreateGroup) (*devices.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGroup", ctx, id, cg)
+	ret0, _ := ret[0].(*devices.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateGroup indicates an expected call of UpdateGroup.
+func (mr *MockGroupRepositoryMockRecorder) UpdateGroup(ctx, id, cg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroup", reflect.TypeOf((*MockGroupRepository)(nil).UpdateGroup), ctx, id, cg)
+}
+
+// MockCompositionRepository is a mock of CompositionRepository interface.
+type MockCompositionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockCompositionRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockCompositionRepositoryMockRecorder is the mock recorder for MockCompositionRepository.
+type MockCompositionRepositoryMockRecorder struct {
+	mock *MockCompositionRepository
+}
+
+// NewMockCompositionRepository creates a new mock instance.
+func NewMockCompositionRepository(ctrl *gomock.Controller) *MockCompositionRepository {
+	mock := &MockCompositionRepository{ctrl: ctrl}
+	mock.recorder = &MockCompositionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCompositionRepository) EXPECT() *MockCompositionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeviceTree mocks base method.
+func (m *MockCompositionRepository) DeviceTree(ctx context.Context, id int64) (*devices.DeviceNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeviceTree", ctx, id)
+	ret0, _ := ret[0].(*devices.DeviceNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeviceTree indicates an expected call of DeviceTree.
+func (mr *MockCompositionRepositoryMockRecorder) DeviceTree(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeviceTree", reflect.TypeOf((*MockCompositionRepository)(nil).DeviceTree), ctx, id)
+}
+
+// SetParent mocks base method.
+func (m *MockCompositionRepository) SetParent(ctx context.Context, deviceId int64, a devices.Attach, version int64) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetParent", ctx, deviceId, a, version)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetParent indicates an expected call of SetParent.
+func (mr *MockCompositionRepositoryMockRecorder) SetParent(ctx, deviceId, a, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetParent", reflect.TypeOf((*MockCompositionRepository)(nil).SetParent), ctx, deviceId, a, version)
+}
+
+// MockHistoryReader is a mock of HistoryReader interface.
+type MockHistoryReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryReaderMockRecorder
+	isgomock struct{}
+}
+
+// MockHistoryReaderMockRecorder is the mock recorder for MockHistoryReader.
+type MockHistoryReaderMockRecorder struct {
+	mock *MockHistoryReader
+}
+
+// NewMockHistoryReader creates a new mock instance.
+func NewMockHistoryReader(ctrl *gomock.Controller) *MockHistoryReader {
+	mock := &MockHistoryReader{ctrl: ctrl}
+	mock.recorder = &MockHistoryReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistoryReader) EXPECT() *MockHistoryReaderMockRecorder {
+	return m.recorder
+}
+
+// History mocks base method.
+func (m *MockHistoryReader) History(ctx context.Context, deviceId int64, f devices.HistoryFilter) ([]devices.HistoryEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, deviceId, f)
+	ret0, _ := ret[0].([]devices.HistoryEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockHistoryReaderMockRecorder) History(ctx, deviceId, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockHistoryReader)(nil).History), ctx, deviceId, f)
+}
+
+// MockLeaseRepository is a mock of LeaseRepository interface.
+type MockLeaseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaseRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLeaseRepositoryMockRecorder is the mock recorder for MockLeaseRepository.
+type MockLeaseRepositoryMockRecorder struct {
+	mock *MockLeaseRepository
+}
+
+// NewMockLeaseRepository creates a new mock instance.
+func NewMockLeaseRepository(ctrl *gomock.Controller) *MockLeaseRepository {
+	mock := &MockLeaseRepository{ctrl: ctrl}
+	mock.recorder = &MockLeaseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaseRepository) EXPECT() *MockLeaseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Checkin mocks base method.
+func (m *MockLeaseRepository) Checkin(ctx context.Context, deviceId int64) (*devices.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkin", ctx, deviceId)
+	ret0, _ := ret[0].(*devices.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkin indicates an expected call of Checkin.
+func (mr *MockLeaseRepositoryMockRecorder) Checkin(ctx, deviceId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkin", reflect.TypeOf((*MockLeaseRepository)(nil).Checkin), ctx, deviceId)
+}
+
+// Checkout mocks base method.
+func (m *MockLeaseRepository) Checkout(ctx context.Context, deviceId int64, c devices.Checkout) (*devices.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkout", ctx, deviceId, c)
+	ret0, _ := ret[0].(*devices.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkout indicates an expected call of Checkout.
+func (mr *MockLeaseRepositoryMockRecorder) Checkout(ctx, deviceId, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkout", reflect.TypeOf((*MockLeaseRepository)(nil).Checkout), ctx, deviceId, c)
+}
+
+// ExpireLeases mocks base method.
+func (m *MockLeaseRepository) ExpireLeases(ctx context.Context, now time.Time) ([]devices.Lease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireLeases", ctx, now)
+	ret0, _ := ret[0].([]devices.Lease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireLeases indicates an expected call of ExpireLeases.
+func (mr *MockLeaseRepositoryMockRecorder) ExpireLeases(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireLeases", reflect.TypeOf((*MockLeaseRepository)(nil).ExpireLeases), ctx, now)
+}
+
+// MockReservationRepository is a mock of ReservationRepository interface.
+type MockReservationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockReservationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockReservationRepositoryMockRecorder is the mock recorder for MockReservationRepository.
+type MockReservationRepositoryMockRecorder struct {
+	mock *MockReservationRepository
+}
+
+// NewMockReservationRepository creates a new mock instance.
+func NewMockReservationRepository(ctrl *gomock.Controller) *MockReservationRepository {
+	mock := &MockReservationRepository{ctrl: ctrl}
+	mock.recorder = &MockReservationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReservationRepository) EXPECT() *MockReservationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CancelReservation mocks base method.
+func (m *MockReservationRepository) CancelReservation(ctx context.Context, deviceId, reservationId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelReservation", ctx, deviceId, reservationId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelReservation indicates an expected call of CancelReservation.
+func (mr *MockReservationRepositoryMockRecorder) CancelReservation(ctx, deviceId, reservationId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReservation", reflect.TypeOf((*MockReservationRepository)(nil).CancelReservation), ctx, deviceId, reservationId)
+}
+
+// Reservations mocks base method.
+func (m *MockReservationRepository) Reservations(ctx context.Context, f devices.ReservationFilter) ([]devices.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reservations", ctx, f)
+	ret0, _ := ret[0].([]devices.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reservations indicates an expected call of Reservations.
+func (mr *MockReservationRepositoryMockRecorder) Reservations(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reservations", reflect.TypeOf((*MockReservationRepository)(nil).Reservations), ctx, f)
+}
+
+// Reserve mocks base method.
+func (m *MockReservationRepository) Reserve(ctx context.Context, deviceId int64, cr devices.CreateReservation) (*devices.Reservation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, deviceId, cr)
+	ret0, _ := ret[0].(*devices.Reservation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockReservationRepositoryMockRecorder) Reserve(ctx, deviceId, cr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockReservationRepository)(nil).Reserve), ctx, deviceId, cr)
+}
+
+// MockMaintenanceRepository is a mock of MaintenanceRepository interface.
+type MockMaintenanceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMaintenanceRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockMaintenanceRepositoryMockRecorder is the mock recorder for MockMaintenanceRepository.
+type MockMaintenanceRepositoryMockRecorder struct {
+	mock *MockMaintenanceRepository
+}
+
+// NewMockMaintenanceRepository creates a new mock instance.
+func NewMockMaintenanceRepository(ctrl *gomock.Controller) *MockMaintenanceRepository {
+	mock := &MockMaintenanceRepository{ctrl: ctrl}
+	mock.recorder = &MockMaintenanceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMaintenanceRepository) EXPECT() *MockMaintenanceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ApplyMaintenance mocks base method.
+func (m *MockMaintenanceRepository) ApplyMaintenance(ctx context.Context, now time.Time) ([]devices.MaintenanceWindow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyMaintenance", ctx, now)
+	ret0, _ := ret[0].([]devices.MaintenanceWindow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyMaintenance indicates an expected call of ApplyMaintenance.
+func (mr *MockMaintenanceRepositoryMockRecorder) ApplyMaintenance(ctx, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyMaintenance", reflect.TypeOf((*MockMaintenanceRepository)(nil).ApplyMaintenance), ctx, now)
+}
+
+// CancelMaintenance mocks base method.
+func (m *MockMaintenanceRepository) CancelMaintenance(ctx context.Context, deviceId, windowId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelMaintenance", ctx, deviceId, windowId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelMaintenance indicates an expected call of CancelMaintenance.
+func (mr *MockMaintenanceRepositoryMockRecorder) CancelMaintenance(ctx, deviceId, windowId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelMaintenance", reflect.TypeOf((*MockMaintenanceRepository)(nil).CancelMaintenance), ctx, deviceId, windowId)
+}
+
+// MaintenanceWindows mocks base method.
+func (m *MockMaintenanceRepository) MaintenanceWindows(ctx context.Context, f devices.MaintenanceFilter) ([]devices.MaintenanceWindow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaintenanceWindows", ctx, f)
+	ret0, _ := ret[0].([]devices.MaintenanceWindow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MaintenanceWindows indicates an expected call of MaintenanceWindows.
+func (mr *MockMaintenanceRepositoryMockRecorder) MaintenanceWindows(ctx, f any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaintenanceWindows", reflect.TypeOf((*MockMaintenanceRepository)(nil).MaintenanceWindows), ctx, f)
+}
+
+// ScheduleMaintenance mocks base method.
+func (m *MockMaintenanceRepository) ScheduleMaintenance(ctx context.Context, deviceId int64, cm devices.CreateMaintenance) (*devices.MaintenanceWindow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleMaintenance", ctx, deviceId, cm)
+	ret0, _ := ret[0].(*devices.MaintenanceWindow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleMaintenance indicates an expected call of ScheduleMaintenance.
+func (mr *MockMaintenanceRepositoryMockRecorder) ScheduleMaintenance(ctx, deviceId, cm any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleMaintenance", reflect.TypeOf((*MockMaintenanceRepository)(nil).ScheduleMaintenance), ctx, deviceId, cm)
+}
+
+// MockTrashRepository is a mock of TrashRepository interface.
+type MockTrashRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTrashRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockTrashRepositoryMockRecorder is the mock recorder for MockTrashRepository.
+type MockTrashRepositoryMockRecorder struct {
+	mock *MockTrashRepository
+}
+
+// NewMockTrashRepository creates a new mock instance.
+func NewMockTrashRepository(ctrl *gomock.Controller) *MockTrashRepository {
+	mock := &MockTrashRepository{ctrl: ctrl}
+	mock.recorder = &MockTrashRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTrashRepository) EXPECT() *MockTrashRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Purge mocks base method.
+func (m *MockTrashRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockTrashRepositoryMockRecorder) Purge(ctx, deletedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockTrashRepository)(nil).Purge), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockTrashRepository) Restore(ctx context.Context, id int64) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockTrashRepositoryMockRecorder) Restore(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockTrashRepository)(nil).Restore), ctx, id)
+}
+
+// Trash mocks base method.
+func (m *MockTrashRepository) Trash(ctx context.Context) ([]devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx)
+	ret0, _ := ret[0].([]devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockTrashRepositoryMockRecorder) Trash(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockTrashRepository)(nil).Trash), ctx)
+}
+
+// MockDatabase is a mock of Database interface.
+type MockDatabase struct {
 	ctrl     *gomock.Controller
-	recorder *MockServiceMockRecorder
+	recorder *MockDatabaseMockRecorder
 	isgomock struct{}
 }
 
-// MockServiceMockRecorder is the mock recorder for MockService.
-type MockServiceMockRecorder struct {
-	mock *MockService
+// MockDatabaseMockRecorder is the mock recorder for MockDatabase.
+type MockDatabaseMockRecorder struct {
+	mock *MockDatabase
 }
 
-// NewMockService creates a new mock instance.
-func NewMockService(ctrl *gomock.Controller) *MockService {
-	mock := &MockService{ctrl: ctrl}
-	mock.recorder = &MockServiceMockRecorder{mock}
+// NewMockDatabase creates a new mock instance.
+func NewMockDatabase(ctrl *gomock.Controller) *MockDatabase {
+	mock := &MockDatabase{ctrl: ctrl}
+	mock.recorder = &MockDatabaseMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockService) EXPECT() *MockServiceMockRecorder {
+func (m *MockDatabase) EXPECT() *MockDatabaseMockRecorder {
 	return m.recorder
 }
 
 // Close mocks base method.
-func (m *MockService) Close() error {
+func (m *MockDatabase) Close() error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Close")
 	ret0, _ := ret[0].(error)
@@ -361,13 +1650,13 @@ func (m *MockService) Close() error {
 }
 
 // Close indicates an expected call of Close.
-func (mr *MockServiceMockRecorder) Close() *gomock.Call {
+func (mr *MockDatabaseMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockService)(nil).Close))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockDatabase)(nil).Close))
 }
 
 // Health mocks base method.
-func (m *MockService) Health() map[string]string {
+func (m *MockDatabase) Health() map[string]string {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Health")
 	ret0, _ := ret[0].(map[string]string)
@@ -375,7 +1664,7 @@ func (m *MockService) Health() map[string]string {
 }
 
 // Health indicates an expected call of Health.
-func (mr *MockServiceMockRecorder) Health() *gomock.Call {
+func (mr *MockDatabaseMockRecorder) Health() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockService)(nil).Health))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockDatabase)(nil).Health))
 }